@@ -36,14 +36,84 @@ func main() {
 		// logs
 		logsSetup()
 
-		// Create a new gambling instance
-		gambling := internal.NewGambling(c.String("config"))
+		// Create a new bot instance
+		bot := internal.NewBot(c.String("config"))
 
 		// Start it
-		return gambling.Start()
+		return bot.Start()
 
 	}
 
+	// Subcommands
+	app.Commands = []cli.Command{
+		{
+			Name:  "history",
+			Usage: "List and inspect past votes of a channel",
+			Flags: []cli.Flag{
+				cli.StringFlag{
+					Name:  "channel",
+					Usage: "Twitch channel, needed if several channels are configured",
+				},
+				cli.IntFlag{
+					Name:  "limit, n",
+					Usage: "Number of votes listed, newest first",
+					Value: 10,
+				},
+			},
+			Action: func(c *cli.Context) error {
+				logsSetup()
+				return internal.ListHistory(os.Stdout, c.GlobalString("config"), c.String("channel"), c.Int("limit"))
+			},
+			Subcommands: []cli.Command{
+				{
+					Name:      "show",
+					Usage:     "Show statistics of a past vote",
+					ArgsUsage: "<vote id>",
+					Flags: []cli.Flag{
+						cli.StringFlag{
+							Name:  "channel",
+							Usage: "Twitch channel, needed if several channels are configured",
+						},
+						cli.StringFlag{
+							Name:  "format, f",
+							Usage: "Statistics format : text, json or csv",
+							Value: "text",
+						},
+					},
+					Action: func(c *cli.Context) error {
+						logsSetup()
+						if c.NArg() < 1 {
+							return cli.NewExitError("A vote id is needed", 1)
+						}
+						return internal.ShowHistory(os.Stdout, c.GlobalString("config"), c.String("channel"), c.Args().First(), c.String("format"))
+					},
+				},
+			},
+		},
+		{
+			Name:      "verify",
+			Usage:     "Draw winners of a past provably fair vote again from its seed",
+			ArgsUsage: "<vote id>",
+			Flags: []cli.Flag{
+				cli.StringFlag{
+					Name:  "channel",
+					Usage: "Twitch channel, needed if several channels are configured",
+				},
+				cli.StringFlag{
+					Name:  "seed",
+					Usage: "Seed revealed in the channel, the archived one if empty",
+				},
+			},
+			Action: func(c *cli.Context) error {
+				logsSetup()
+				if c.NArg() < 1 {
+					return cli.NewExitError("A vote id is needed", 1)
+				}
+				return internal.VerifyDraws(os.Stdout, c.GlobalString("config"), c.String("channel"), c.Args().First(), c.String("seed"))
+			},
+		},
+	}
+
 	// Run
 	err := app.Run(os.Args)
 	if err != nil {
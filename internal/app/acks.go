@@ -0,0 +1,174 @@
+package app
+
+import (
+	"sync"
+	"time"
+
+	"github.com/apex/log"
+)
+
+// Default number of acks waiting to be sent
+const bufferSize = 500
+
+// Minimum delay between two rate limit warnings in a channel
+const warningDelay = 15 * time.Second
+
+// VoteAck is a struct containing vote acknolegment send later if rate limit is reached
+type VoteAck struct {
+	message  string
+	Username string
+}
+
+// NewVoteAck is used to init a VoteAck struct
+func NewVoteAck(message string, username string) VoteAck {
+	return VoteAck{
+		message:  message,
+		Username: username,
+	}
+}
+
+// AckStats is a structure containing ack queue metrics, since the bot started
+type AckStats struct {
+	// Acks waiting to be sent
+	Queued int `json:"queued"`
+	// Acks added to the queue
+	Enqueued uint64 `json:"enqueued"`
+	// Acks sent
+	Sent uint64 `json:"sent"`
+	// Acks replaced by a newer ack for the same user
+	Coalesced uint64 `json:"coalesced"`
+	// Acks dropped because the queue was full
+	Dropped uint64 `json:"dropped"`
+	// Acks dropped with their vote, by a reset or a delete
+	Cleared uint64 `json:"cleared"`
+}
+
+// AckQueue is used to store ack messages while rate limit is reached
+// enqueuing never blocks : only the latest ack of a user is kept, and the oldest ack is dropped when the queue is full
+type AckQueue struct {
+	mutex    sync.Mutex
+	capacity int
+	// users, oldest first
+	order []string
+	// latest message, by user
+	pending map[string]string
+	stats   AckStats
+}
+
+// NewAckQueue is used to init an AckQueue struct
+func NewAckQueue(capacity int) *AckQueue {
+	return &AckQueue{
+		capacity: capacity,
+		pending:  make(map[string]string),
+	}
+}
+
+// Push adds an ack to the queue, without blocking
+func (q *AckQueue) Push(ack VoteAck) {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	// the user is already waiting, only send the latest ack
+	if _, ok := q.pending[ack.Username]; ok {
+		q.pending[ack.Username] = ack.message
+		q.stats.Coalesced++
+		return
+	}
+
+	// queue is full, drop the oldest ack
+	if len(q.order) >= q.capacity {
+		oldest := q.order[0]
+		q.order = q.order[1:]
+		delete(q.pending, oldest)
+		q.stats.Dropped++
+
+		log.WithFields(log.Fields{
+			"user":    oldest,
+			"dropped": q.stats.Dropped,
+		}).Warn("ACKs queue is full, oldest message dropped")
+	}
+
+	q.order = append(q.order, ack.Username)
+	q.pending[ack.Username] = ack.message
+	q.stats.Enqueued++
+}
+
+// Pop removes the oldest ack from the queue, false if the queue is empty
+func (q *AckQueue) Pop() (VoteAck, bool) {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	if len(q.order) == 0 {
+		return VoteAck{}, false
+	}
+
+	user := q.order[0]
+	q.order = q.order[1:]
+	message := q.pending[user]
+	delete(q.pending, user)
+
+	return NewVoteAck(message, user), true
+}
+
+// Clear drops all waiting acks
+func (q *AckQueue) Clear() {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	q.stats.Cleared += uint64(len(q.order))
+	q.order = nil
+	q.pending = make(map[string]string)
+}
+
+// Len returns the number of waiting acks
+func (q *AckQueue) Len() int {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	return len(q.order)
+}
+
+// markSent counts an ack as sent
+func (q *AckQueue) markSent() {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	q.stats.Sent++
+}
+
+// Stats returns the queue metrics
+func (q *AckQueue) Stats() AckStats {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	stats := q.stats
+	stats.Queued = len(q.order)
+
+	return stats
+}
+
+// ack is used to send an acknowledgement to a user, if the bot is verified
+// the ack is sent right away if rate limits allow it, and later otherwise, it never blocks
+func (g *Gambling) ack(user string, message string) {
+
+	// ensure bot is verified to send whispers
+	if !g.Config.Verified {
+		return
+	}
+
+	g.Acks.Push(NewVoteAck(message, user))
+	g.Scheduler.Flush()
+
+	// still waiting, let viewers know once in a while
+	if g.Acks.Len() > 0 {
+		log.WithFields(log.Fields{
+			"message": message,
+			"user":    user,
+		}).Info("Message added to ACKs queue")
+
+		if now().Sub(g.warnedAt) >= warningDelay {
+			g.warnedAt = now()
+			g.say(g.text("rate_limit", nil))
+		}
+	}
+}
@@ -0,0 +1,127 @@
+package app
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAckQueueCoalesce(t *testing.T) {
+	q := NewAckQueue(10)
+
+	q.Push(NewVoteAck("first", "alice"))
+	q.Push(NewVoteAck("other", "bob"))
+	q.Push(NewVoteAck("second", "alice"))
+
+	assert.Equal(t, 2, q.Len())
+
+	ack, ok := q.Pop()
+	assert.True(t, ok)
+	assert.Equal(t, NewVoteAck("second", "alice"), ack)
+
+	ack, ok = q.Pop()
+	assert.True(t, ok)
+	assert.Equal(t, NewVoteAck("other", "bob"), ack)
+
+	_, ok = q.Pop()
+	assert.False(t, ok)
+
+	assert.Equal(t, AckStats{Enqueued: 2, Coalesced: 1}, q.Stats())
+}
+
+func TestAckQueueOverflow(t *testing.T) {
+	q := NewAckQueue(2)
+
+	q.Push(NewVoteAck("a", "alice"))
+	q.Push(NewVoteAck("b", "bob"))
+	q.Push(NewVoteAck("c", "carol"))
+
+	assert.Equal(t, AckStats{Queued: 2, Enqueued: 3, Dropped: 1}, q.Stats())
+
+	ack, _ := q.Pop()
+	assert.Equal(t, "bob", ack.Username)
+	ack, _ = q.Pop()
+	assert.Equal(t, "carol", ack.Username)
+}
+
+func TestAckQueueClear(t *testing.T) {
+	q := NewAckQueue(10)
+
+	q.Push(NewVoteAck("a", "alice"))
+	q.Push(NewVoteAck("b", "bob"))
+	q.Clear()
+
+	assert.Equal(t, 0, q.Len())
+	assert.Equal(t, AckStats{Enqueued: 2, Cleared: 2}, q.Stats())
+}
+
+func TestAcksNeverBlock(t *testing.T) {
+	conf := testConf()
+	conf.Limits.Whisper = Bucket{Minute: 1}
+	g, fake := newTestGambling(t, conf)
+
+	fake.send("chan", "admin", "!gamble create val pl")
+	fake.Said()
+
+	// more voters than the queue can hold
+	start := time.Now()
+	for i := 0; i < bufferSize+101; i++ {
+		fake.send("chan", fmt.Sprintf("viewer%d", i), "!gamble vote val")
+	}
+	assert.True(t, time.Since(start) < 5*time.Second)
+
+	// only the first one is sent, viewers are warned once
+	assert.Len(t, fake.Whispered(), 1)
+	assert.Equal(t, []string{"Warning, whisper rate limit reached, you may receive your vote acknowledgement later"}, fake.Said())
+
+	stats := g.Acks.Stats()
+	assert.Equal(t, uint64(bufferSize+101), stats.Enqueued)
+	assert.Equal(t, uint64(1), stats.Sent)
+	assert.Equal(t, uint64(100), stats.Dropped)
+	assert.Equal(t, bufferSize, stats.Queued)
+
+	// acks of a reset vote are dropped
+	fake.send("chan", "admin", "!gamble reset")
+	assert.Equal(t, 0, g.Acks.Len())
+	assert.Equal(t, uint64(bufferSize), g.Acks.Stats().Cleared)
+}
+
+func TestLateAcks(t *testing.T) {
+	conf := testConf()
+	conf.API.Token = "secret"
+	conf.Limits.Whisper = Bucket{Second: 1}
+	b, fake := newTestBot(t, conf)
+	clock := b.Scheduler.clock.(*fakeClock)
+
+	fake.send("chan", "admin", "!gamble create val pl")
+	fake.send("chan", "carol", "!gamble vote val")
+	fake.send("chan", "alice", "!gamble vote val")
+	fake.send("chan", "bob", "!gamble vote pl")
+	fake.send("chan", "alice", "!gamble vote pl")
+
+	valid := "For your information, I correctly handled your vote for %s (sent on 2020-04-02)"
+	assert.Equal(t, []sentMessage{{To: "carol", Message: fmt.Sprintf(valid, "val")}}, fake.Whispered())
+
+	// one ack per second, only the latest ack of each user is sent
+	var whispered []sentMessage
+	for i := 1; i <= 2; i++ {
+		clock.Advance(time.Second)
+		assert.Eventually(t, func() bool {
+			whispered = append(whispered, fake.Whispered()...)
+			return len(whispered) == i
+		}, time.Second, time.Millisecond)
+	}
+
+	assert.Equal(t, []sentMessage{
+		{To: "alice", Message: fmt.Sprintf(valid, "pl")},
+		{To: "bob", Message: fmt.Sprintf(valid, "pl")},
+	}, whispered)
+
+	code, res := apiCall(t, b, http.MethodGet, "/api/channels/chan/acks", "", "secret")
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, float64(3), res["sent"])
+	assert.Equal(t, float64(1), res["coalesced"])
+}
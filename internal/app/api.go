@@ -0,0 +1,237 @@
+package app
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/apex/log"
+	twitch "github.com/gempir/go-twitch-irc/v2"
+)
+
+// apiUser is the user used to run commands received by the HTTP API
+var apiUser = twitch.User{Name: "api", DisplayName: "API"}
+
+// Tally is a structure containing the number of votes for a choice
+type Tally struct {
+	Choice  string  `json:"choice"`
+	Votes   int     `json:"votes"`
+	Percent float64 `json:"percent"`
+}
+
+// VoteState is a structure describing the current vote of a channel
+type VoteState struct {
+	Channel string  `json:"channel"`
+	Open    bool    `json:"open"`
+	Mode    string  `json:"mode"`
+	Total   int     `json:"total"`
+	Tallies []Tally `json:"tallies"`
+	// Weight of each choice, for weighted votes only
+	Weighted []WeightedTally `json:"weighted,omitempty"`
+	Runoff   *Runoff         `json:"runoff,omitempty"`
+	Numbers  *NumberSummary  `json:"numbers,omitempty"`
+	Winners  []string        `json:"winners"`
+	Outcome  string          `json:"outcome,omitempty"`
+	Deadline *time.Time      `json:"deadline,omitempty"`
+	// Hash of the seed used to draw winners, in provably fair mode
+	SeedHash string `json:"seed_hash,omitempty"`
+	// Seed used to draw winners, once revealed
+	Seed string `json:"seed,omitempty"`
+}
+
+// apiRequest is the body of a command sent to the HTTP API
+type apiRequest struct {
+	Args []string `json:"args"`
+}
+
+// apiError is the body of an HTTP API error
+type apiError struct {
+	Error string `json:"error"`
+}
+
+// state returns the current vote state, with a tally for each choice
+func (g *Gambling) state() VoteState {
+	st := NewStatistics(g.CurrentVote)
+
+	state := VoteState{
+		Channel: g.Config.Twitch.Channel,
+		Open:    g.CurrentVote.IsOpen,
+		Mode:    g.CurrentVote.mode(),
+		Total:   st.Total,
+		Tallies: []Tally{},
+		Runoff:  st.Runoff,
+		Numbers: st.Numbers,
+		// copied, the state is read outside of the event loop
+		Winners: append([]string{}, g.CurrentVote.names(g.CurrentVote.Winners)...),
+		Outcome: g.CurrentVote.Outcome,
+	}
+
+	if !g.CurrentVote.Deadline.IsZero() {
+		deadline := g.CurrentVote.Deadline
+		state.Deadline = &deadline
+	}
+
+	for _, p := range g.CurrentVote.choiceList(st) {
+		t := Tally{Choice: p, Votes: len(st.Transformed[p])}
+		if st.Total > 0 {
+			t.Percent = float64(t.Votes) / float64(st.Total) * 100
+		}
+		state.Tallies = append(state.Tallies, t)
+	}
+
+	if st.Weighted != nil {
+		state.Weighted = weightedTallies(g.CurrentVote, st)
+	}
+
+	// the seed is kept secret until winners are rolled
+	state.SeedHash = g.CurrentVote.SeedHash
+	if len(g.CurrentVote.Winners) > 0 {
+		state.Seed = g.CurrentVote.Seed
+	}
+
+	return state
+}
+
+// apiHandler returns the HTTP API handler
+//
+//	GET  /api/channels/<channel>/vote           current vote state
+//	POST /api/channels/<channel>/vote/<command> run an admin command, returns the new vote state
+//	GET  /api/channels/<channel>/acks           ack queue metrics
+//	GET  /overlay/<channel>                     overlay page, no authentication needed
+func (b *Bot) apiHandler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/channels/", b.authenticated(b.handleAPIVote))
+	mux.HandleFunc("/overlay/", b.handleOverlay)
+
+	return mux
+}
+
+// serveAPI is used to start the HTTP API, if configured
+func (b *Bot) serveAPI() {
+	if b.Config.API.Address == "" {
+		return
+	}
+
+	// never expose commands without authentication
+	if b.Config.API.Token == "" {
+		log.Error("An API token is required to start the HTTP API")
+		return
+	}
+
+	log.WithField("address", b.Config.API.Address).Info("HTTP API is starting")
+
+	err := http.ListenAndServe(b.Config.API.Address, b.apiHandler())
+	if err != nil {
+		log.WithError(err).Error("HTTP API stopped")
+	}
+}
+
+// authenticated ensures a request contains the API token as bearer token
+func (b *Bot) authenticated(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		token := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+
+		if b.Config.API.Token == "" || subtle.ConstantTimeCompare([]byte(token), []byte(b.Config.API.Token)) != 1 {
+			writeJSON(w, http.StatusUnauthorized, apiError{Error: "invalid token"})
+			return
+		}
+
+		next(w, r)
+	}
+}
+
+// handle a call to the vote of a channel
+func (b *Bot) handleAPIVote(w http.ResponseWriter, r *http.Request) {
+
+	// /api/channels/<channel>/vote[/<command>] or /api/channels/<channel>/acks
+	parts := strings.Split(strings.Trim(strings.TrimPrefix(r.URL.Path, "/api/channels/"), "/"), "/")
+	if len(parts) < 2 || len(parts) > 3 {
+		writeJSON(w, http.StatusNotFound, apiError{Error: "not found"})
+		return
+	}
+
+	g, ok := b.Sessions[strings.ToLower(parts[0])]
+	if !ok {
+		writeJSON(w, http.StatusNotFound, apiError{Error: "unknown channel"})
+		return
+	}
+
+	// ack queue metrics, the queue is safe to read outside of the event loop
+	if len(parts) == 2 && parts[1] == "acks" {
+		if r.Method != http.MethodGet {
+			writeJSON(w, http.StatusMethodNotAllowed, apiError{Error: "method not allowed"})
+			return
+		}
+
+		writeJSON(w, http.StatusOK, g.Acks.Stats())
+		return
+	}
+
+	if parts[1] != "vote" {
+		writeJSON(w, http.StatusNotFound, apiError{Error: "not found"})
+		return
+	}
+
+	// vote state
+	if len(parts) == 2 {
+		if r.Method != http.MethodGet {
+			writeJSON(w, http.StatusMethodNotAllowed, apiError{Error: "method not allowed"})
+			return
+		}
+
+		var state VoteState
+		g.do(func() {
+			state = g.state()
+		})
+
+		writeJSON(w, http.StatusOK, state)
+		return
+	}
+
+	// admin command
+	if r.Method != http.MethodPost {
+		writeJSON(w, http.StatusMethodNotAllowed, apiError{Error: "method not allowed"})
+		return
+	}
+
+	c, ok := commands[parts[2]]
+	if !ok || !c.admin {
+		writeJSON(w, http.StatusNotFound, apiError{Error: "unknown command"})
+		return
+	}
+
+	var req apiRequest
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil && err != io.EOF {
+		writeJSON(w, http.StatusBadRequest, apiError{Error: "invalid body"})
+		return
+	}
+
+	log.WithFields(log.Fields{
+		"channel": g.Config.Twitch.Channel,
+		"command": parts[2],
+		"args":    req.Args,
+	}).Info("Command received from HTTP API")
+
+	var state VoteState
+	g.do(func() {
+		c.run(g, apiUser, req.Args)
+		state = g.state()
+	})
+
+	writeJSON(w, http.StatusOK, state)
+}
+
+// writeJSON is used to write a JSON response
+func writeJSON(w http.ResponseWriter, status int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+
+	err := json.NewEncoder(w).Encode(body)
+	if err != nil {
+		log.WithError(err).Error("Error writing HTTP response")
+	}
+}
@@ -0,0 +1,120 @@
+package app
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// apiCall is used to call the HTTP API and decode the JSON response
+func apiCall(t *testing.T, b *Bot, method string, path string, body string, token string) (int, map[string]interface{}) {
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	rec := httptest.NewRecorder()
+	b.apiHandler().ServeHTTP(rec, req)
+
+	var res map[string]interface{}
+	assert.Nil(t, json.Unmarshal(rec.Body.Bytes(), &res))
+
+	return rec.Code, res
+}
+
+func TestAPIAuthentication(t *testing.T) {
+	conf := testConf()
+	conf.API.Token = "secret"
+	b, _ := newTestBot(t, conf)
+
+	code, _ := apiCall(t, b, http.MethodGet, "/api/channels/chan/vote", "", "")
+	assert.Equal(t, http.StatusUnauthorized, code)
+
+	code, _ = apiCall(t, b, http.MethodGet, "/api/channels/chan/vote", "", "nope")
+	assert.Equal(t, http.StatusUnauthorized, code)
+
+	code, _ = apiCall(t, b, http.MethodGet, "/api/channels/chan/vote", "", "secret")
+	assert.Equal(t, http.StatusOK, code)
+
+	// no token configured, nobody can use the API
+	b, _ = newTestBot(t, testConf())
+	code, _ = apiCall(t, b, http.MethodGet, "/api/channels/chan/vote", "", "")
+	assert.Equal(t, http.StatusUnauthorized, code)
+}
+
+func TestAPIVote(t *testing.T) {
+	conf := testConf()
+	conf.API.Token = "secret"
+	b, fake := newTestBot(t, conf)
+
+	code, res := apiCall(t, b, http.MethodPost, "/api/channels/chan/vote/create", `{"args": ["val", "pl"]}`, "secret")
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, true, res["open"])
+	assert.Equal(t, []string{"There is a new vote! You can vote with '!gamble vote <vote> (choices are : val or pl)'"}, fake.Said())
+
+	fake.send("chan", "alice", "!gamble vote val")
+	fake.send("chan", "bob", "!gamble vote val")
+	fake.send("chan", "carol", "!gamble vote pl")
+	fake.send("chan", "dave", "!gamble vote val")
+
+	code, res = apiCall(t, b, http.MethodGet, "/api/channels/chan/vote", "", "secret")
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, float64(4), res["total"])
+	assert.Equal(t, []interface{}{
+		map[string]interface{}{"choice": "val", "votes": float64(3), "percent": float64(75)},
+		map[string]interface{}{"choice": "pl", "votes": float64(1), "percent": float64(25)},
+	}, res["tallies"])
+
+	code, res = apiCall(t, b, http.MethodPost, "/api/channels/chan/vote/close", "", "secret")
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, false, res["open"])
+
+	code, res = apiCall(t, b, http.MethodPost, "/api/channels/chan/vote/roll", `{"args": ["pl"]}`, "secret")
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, []interface{}{"carol"}, res["winners"])
+
+	// a closed vote can not be reset
+	code, res = apiCall(t, b, http.MethodPost, "/api/channels/chan/vote/reset", "", "secret")
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, float64(4), res["total"])
+
+	code, res = apiCall(t, b, http.MethodPost, "/api/channels/chan/vote/delete", "", "secret")
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, []interface{}{}, res["tallies"])
+
+	// chat messages are the same as chat commands
+	said := fake.Said()
+	assert.Equal(t, "Vote is now closed, time for statistics ! Participants : 4 | val : 3 (75.00%), pl : 1 (25.00%)", said[0])
+	assert.Equal(t, "@admin  : And... The winner is... carol", said[1])
+	assert.Equal(t, "A closed vote can not be reset, delete it with '!gamble delete' and create a new one", said[2])
+	assert.Equal(t, "Vote deleted !", said[3])
+}
+
+func TestAPIErrors(t *testing.T) {
+	conf := testConf()
+	conf.API.Token = "secret"
+	b, _ := newTestBot(t, conf)
+
+	code, _ := apiCall(t, b, http.MethodGet, "/api/channels/unknown/vote", "", "secret")
+	assert.Equal(t, http.StatusNotFound, code)
+
+	code, _ = apiCall(t, b, http.MethodGet, "/api/channels/chan/nope", "", "secret")
+	assert.Equal(t, http.StatusNotFound, code)
+
+	code, _ = apiCall(t, b, http.MethodPost, "/api/channels/chan/vote", "", "secret")
+	assert.Equal(t, http.StatusMethodNotAllowed, code)
+
+	code, _ = apiCall(t, b, http.MethodGet, "/api/channels/chan/vote/close", "", "secret")
+	assert.Equal(t, http.StatusMethodNotAllowed, code)
+
+	// only admin commands are exposed
+	code, _ = apiCall(t, b, http.MethodPost, "/api/channels/chan/vote/vote", "", "secret")
+	assert.Equal(t, http.StatusNotFound, code)
+
+	code, _ = apiCall(t, b, http.MethodPost, "/api/channels/chan/vote/create", "{", "secret")
+	assert.Equal(t, http.StatusBadRequest, code)
+}
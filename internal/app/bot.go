@@ -0,0 +1,124 @@
+package app
+
+import (
+	"strings"
+
+	"github.com/apex/log"
+	twitch "github.com/gempir/go-twitch-irc/v2"
+)
+
+// Bot is a structure connecting a chat transport to a Gambling instance for each served channel
+type Bot struct {
+	// Config from yaml file
+	Config Conf
+	// Chat transport, Twitch client by default
+	Transport ChatTransport
+	// Gambling instances, by channel name
+	Sessions map[string]*Gambling
+	// Outbound messages scheduler, shared by all channels
+	Scheduler *Scheduler
+}
+
+// NewBot func create a new Bot struct from a config file
+func NewBot(confPath string) *Bot {
+	// Parse config
+	var conf Conf
+	conf.getConf(confPath)
+
+	// Setup twitch client
+	return NewBotWithTransport(conf, NewTwitchTransport(conf.Twitch.Username, conf.Twitch.Oauth))
+}
+
+// NewBotWithTransport func create a new Bot struct using a custom chat transport
+func NewBotWithTransport(conf Conf, transport ChatTransport) *Bot {
+	return newBot(conf, transport, systemClock{})
+}
+
+// newBot func create a new Bot struct using a custom chat transport and clock
+func newBot(conf Conf, transport ChatTransport, clock Clock) *Bot {
+	// Empty new struct
+	b := new(Bot)
+
+	// Config and transport
+	b.Config = conf
+	b.Transport = transport
+	b.Sessions = make(map[string]*Gambling)
+
+	// Rate limits are tied to the bot account, so they are shared by all channels
+	b.Scheduler = NewScheduler(conf.Limits, transport, clock)
+
+	// Prize codes must never be delivered twice, so they are shared by all channels
+	var prizes *Inventory
+	if conf.Prizes.File != "" {
+		var err error
+		prizes, err = NewInventory(conf.Prizes.File, conf.Storage.Dir)
+		if err != nil {
+			log.WithError(err).Fatal("Error loading prizes")
+		}
+	}
+
+	// One Gambling instance per channel
+	for _, c := range conf.channelConfs() {
+		g := NewGambling(c, transport)
+		g.Scheduler = b.Scheduler
+		g.Prizes = prizes
+		b.Scheduler.AddAckQueue(g.Acks)
+		b.Sessions[c.Twitch.Channel] = g
+	}
+
+	// Plug function on Twitch events
+	b.twitchOnEventSetup()
+
+	// Join Twitch channels
+	b.join()
+
+	return b
+}
+
+// Link Twitch events to dedicated functions
+func (b *Bot) twitchOnEventSetup() {
+	// Message handler
+	b.Transport.OnPrivateMessage(b.onPrivateMessage)
+
+	// On connect handler
+	b.Transport.OnConnect(func() {
+		for _, g := range b.Sessions {
+			g.do(g.onConnect)
+		}
+	})
+}
+
+// onPrivateMessage is used to route a channel message to the Gambling instance of this channel
+func (b *Bot) onPrivateMessage(message twitch.PrivateMessage) {
+	g, ok := b.Sessions[strings.ToLower(message.Channel)]
+	if !ok {
+		log.WithField("channel", message.Channel).Warn("Message received from an unknown channel")
+		return
+	}
+
+	g.do(func() {
+		g.onPrivateMessage(message)
+	})
+}
+
+// Join all channels
+func (b *Bot) join() {
+	var channels []string
+
+	// follow config order
+	for _, c := range b.Config.channelConfs() {
+		channels = append(channels, c.Twitch.Channel)
+	}
+
+	b.Transport.Join(channels...)
+}
+
+// Start is used to connect a gamble-bot instance to twitch channels, and start the bot
+func (b *Bot) Start() error {
+	log.WithField("channels", len(b.Sessions)).Info("Bot instance is starting")
+
+	// HTTP API, if configured
+	go b.serveAPI()
+
+	return b.Transport.Connect()
+}
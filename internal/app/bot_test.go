@@ -0,0 +1,51 @@
+package app
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMultiChannel(t *testing.T) {
+	conf := testConf()
+	conf.Channels = []ChannelConf{
+		{Name: "first"},
+		{Name: "second", Admins: []string{"streamer"}, Prefix: "!bet", Hello: "Salut"},
+	}
+
+	b, fake := newTestBot(t, conf)
+	assert.Nil(t, b.Start())
+
+	assert.Equal(t, []string{"first", "second"}, fake.joined)
+	assert.Equal(t, []string{"Hello"}, fake.SaidIn("first"))
+	assert.Equal(t, []string{"Salut"}, fake.SaidIn("second"))
+
+	// admins are per channel
+	fake.send("second", "admin", "!bet create a b")
+	assert.Nil(t, fake.SaidIn("second"))
+
+	fake.send("first", "admin", "!gamble create val pl")
+	fake.send("second", "streamer", "!bet create a b")
+	assert.Equal(t, []string{"There is a new vote! You can vote with '!gamble vote <vote> (choices are : val or pl)'"}, fake.SaidIn("first"))
+	assert.Equal(t, []string{"There is a new vote! You can vote with '!bet vote <vote> (choices are : a or b)'"}, fake.SaidIn("second"))
+
+	// votes never leak into another channel
+	fake.send("first", "alice", "!gamble vote val")
+	fake.send("second", "alice", "!bet vote val")
+	fake.send("second", "bob", "!bet vote b")
+
+	assert.Equal(t, map[string]string{"id-alice": "val"}, b.Sessions["first"].CurrentVote.Votes)
+	assert.Equal(t, map[string]string{"id-bob": "b"}, b.Sessions["second"].CurrentVote.Votes)
+
+	// prefix is per channel too
+	fake.send("first", "bob", "!bet vote pl")
+	assert.Len(t, b.Sessions["first"].CurrentVote.Votes, 1)
+
+	fake.send("first", "admin", "!gamble close")
+	assert.True(t, b.Sessions["second"].CurrentVote.IsOpen)
+	assert.False(t, b.Sessions["first"].CurrentVote.IsOpen)
+
+	// messages from unknown channels are ignored
+	fake.send("third", "admin", "!gamble delete")
+	assert.Nil(t, fake.SaidIn("third"))
+}
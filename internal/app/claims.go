@@ -0,0 +1,267 @@
+package app
+
+import (
+	"strings"
+	"time"
+
+	"github.com/apex/log"
+	twitch "github.com/gempir/go-twitch-irc/v2"
+)
+
+// Claim status of a winner
+const (
+	// Rolled, the prize is not claimed yet
+	claimPending = "pending"
+	// The winner typed the claim command
+	claimClaimed = "claimed"
+	// The winner did not claim the prize in time, or was rerolled
+	claimForfeit = "forfeit"
+)
+
+// Claim is a structure containing the claim status of a winner
+type Claim struct {
+	Status string
+	// Date before which the winner must claim the prize, zero if there is no timeout
+	Deadline time.Time `json:",omitempty"`
+	// Another winner was rolled instead
+	Replaced bool `json:",omitempty"`
+	// Prize whose code was delivered to the winner, empty if none
+	Prize string `json:",omitempty"`
+}
+
+// claimStatus returns the claim status of a winner, winners stored by older versions are pending
+func (v *Vote) claimStatus(id string) string {
+	if c, ok := v.Claims[id]; ok && c.Status != "" {
+		return c.Status
+	}
+
+	return claimPending
+}
+
+// setClaim is used to change the claim status of a winner, the deadline is kept
+func (v *Vote) setClaim(id string, status string) {
+	if v.Claims == nil {
+		v.Claims = make(map[string]Claim)
+	}
+
+	c := v.Claims[id]
+	c.Status = status
+	v.Claims[id] = c
+}
+
+// wonBy returns true if the user won the vote, forfeited winners excepted
+func (v *Vote) wonBy(id string) bool {
+	for _, w := range v.Winners {
+		if w == id && v.claimStatus(id) != claimForfeit {
+			return true
+		}
+	}
+
+	return false
+}
+
+// winnerList returns names of winners, with their claim status once claimed or forfeited
+func (v *Vote) winnerList(c *Catalogue) []string {
+	var res []string
+
+	for _, w := range v.Winners {
+		name := v.name(w)
+		if status := v.claimStatus(w); status != claimPending {
+			name = c.render("winners."+status, vars{"Winner": name})
+		}
+		res = append(res, name)
+	}
+
+	return res
+}
+
+// findWinner returns the ID of the winner with the given login or display name, empty if there is none
+func (v *Vote) findWinner(name string) string {
+	name = strings.TrimPrefix(name, "@")
+
+	for _, w := range v.Winners {
+		voter := v.Voters[w]
+		if strings.EqualFold(name, voter.Name) || strings.EqualFold(name, voter.DisplayName) || name == w {
+			return w
+		}
+	}
+
+	return ""
+}
+
+// lastWinner returns the ID of the last winner who can be rerolled, neither claimed nor replaced, empty if there is none
+func (v *Vote) lastWinner() string {
+	for i := len(v.Winners) - 1; i >= 0; i-- {
+		w := v.Winners[i]
+		if v.claimStatus(w) != claimClaimed && !v.Claims[w].Replaced {
+			return w
+		}
+	}
+
+	return ""
+}
+
+// drawOf returns how a winner was rolled, winners stored by older versions were rolled in the team they voted for
+func (v *Vote) drawOf(id string) Draw {
+	if len(v.Draws) == len(v.Winners) {
+		for i, w := range v.Winners {
+			if w == id {
+				return v.Draws[i]
+			}
+		}
+	}
+
+	return Draw{Team: v.Votes[id]}
+}
+
+// openClaim is used to mark a new winner as pending, with a deadline if winners must claim their prize in time
+func (g *Gambling) openClaim(id string) {
+	if g.CurrentVote.Claims == nil {
+		g.CurrentVote.Claims = make(map[string]Claim)
+	}
+
+	c := Claim{Status: claimPending}
+	if g.Config.Rolls.ClaimTimeout > 0 {
+		c.Deadline = g.clockNow().Add(g.Config.Rolls.ClaimTimeout)
+	}
+	g.CurrentVote.Claims[id] = c
+
+	g.startClaimTimer(id)
+}
+
+// startClaimTimer is used to forfeit a pending winner once its deadline is reached
+func (g *Gambling) startClaimTimer(id string) {
+	c := g.CurrentVote.Claims[id]
+	if c.Status != claimPending || c.Deadline.IsZero() {
+		return
+	}
+
+	vote := g.CurrentVote.ID
+
+	g.after(c.Deadline, func() {
+		g.expireClaim(vote, id)
+	})
+}
+
+// resumeClaims is used to restart deadlines of pending winners, after a restart
+func (g *Gambling) resumeClaims() {
+	for id := range g.CurrentVote.Claims {
+		g.startClaimTimer(id)
+	}
+}
+
+// expireClaim is used to forfeit a winner who did not claim the prize in time
+func (g *Gambling) expireClaim(vote string, id string) {
+	// the vote may have been replaced, or the prize claimed or rerolled since
+	if g.CurrentVote.ID != vote || g.CurrentVote.claimStatus(id) != claimPending {
+		return
+	}
+
+	g.CurrentVote.setClaim(id, claimForfeit)
+
+	g.saveVote()
+	g.publish("forfeit")
+
+	log.WithField("user", id).Info("Prize not claimed in time")
+
+	g.sayAt(g.text("claim.expired", vars{"Winner": g.CurrentVote.name(id), "Login": g.CurrentVote.login(id)}), g.admins())
+}
+
+// claimUsage returns how winners claim their prize, empty if they do not need to
+func (g *Gambling) claimUsage() string {
+	if g.Config.Rolls.ClaimTimeout <= 0 {
+		return ""
+	}
+
+	return g.text("roll.claim", vars{"Timeout": g.Config.Rolls.ClaimTimeout})
+}
+
+// handle a prize claimed by a winner
+func (g *Gambling) handleClaim(user twitch.User, args []string) {
+
+	id := userID(user)
+
+	if !g.CurrentVote.wonBy(id) {
+		g.sayAt(g.text("claim.none", nil), []string{user.Name})
+		return
+	}
+
+	if g.CurrentVote.claimStatus(id) == claimClaimed {
+		g.sayAt(g.text("claim.claimed", nil), []string{user.Name})
+		return
+	}
+
+	g.CurrentVote.setClaim(id, claimClaimed)
+
+	g.saveVote()
+	g.publish("claim")
+
+	log.WithField("user", user.Name).Info("Prize claimed")
+
+	// codes are only delivered once claimed, if winners must claim in time
+	g.deliverPrizes([]string{id})
+	if g.CurrentVote.Claims[id].Prize != "" {
+		g.sayAt(g.text("claim.code", nil), []string{user.Name})
+		return
+	}
+
+	g.sayAt(g.text("claim", nil), []string{user.Name})
+}
+
+// handle a reroll, the last (or a named) winner forfeits and a new winner is rolled in the same team
+func (g *Gambling) handleReroll(user twitch.User, args []string) {
+
+	if g.CurrentVote.IsOpen || len(g.CurrentVote.Winners) == 0 {
+		g.sayAt(g.text("winners.none", nil), g.admins())
+		return
+	}
+
+	forfeited := g.CurrentVote.lastWinner()
+	if len(args) > 0 {
+		forfeited = g.CurrentVote.findWinner(args[0])
+		if forfeited == "" {
+			g.sayAt(g.text("reroll.unknown", vars{"Name": args[0]}), g.admins())
+			return
+		}
+	}
+	if forfeited == "" {
+		g.sayAt(g.text("reroll.none", nil), g.admins())
+		return
+	}
+
+	name := g.CurrentVote.name(forfeited)
+
+	if g.CurrentVote.claimStatus(forfeited) == claimClaimed {
+		g.sayAt(g.text("reroll.claimed", vars{"Winner": name}), g.admins())
+		return
+	}
+	// a winner who did not claim the prize in time is replaced only once
+	if g.CurrentVote.Claims[forfeited].Replaced {
+		g.sayAt(g.text("reroll.replaced", vars{"Winner": name}), g.admins())
+		return
+	}
+
+	g.CurrentVote.setClaim(forfeited, claimForfeit)
+
+	log.WithField("user", forfeited).Info("Winner forfeited")
+
+	winners, err := g.rollWinners(g.CurrentVote.drawOf(forfeited), 1)
+	if err != nil {
+		// the forfeit is kept even if nobody can replace the winner yet
+		g.saveVote()
+		g.publish("forfeit")
+		g.sayAt(g.text("reroll.failed", vars{"Winner": name, "Error": err.Error()}), g.admins())
+		return
+	}
+
+	c := g.CurrentVote.Claims[forfeited]
+	c.Replaced = true
+	g.CurrentVote.Claims[forfeited] = c
+	g.saveVote()
+
+	g.announceAt(g.text("reroll", vars{"Winner": name, "New": g.CurrentVote.name(winners[0]), "Claim": g.claimUsage()}), g.admins())
+
+	g.revealSeed()
+	g.sendPrizes(winners)
+	g.notifyWinners(winners)
+}
@@ -0,0 +1,101 @@
+package app
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReroll(t *testing.T) {
+	g, fake := newTestGambling(t, testConf())
+
+	fake.send("chan", "admin", "!gamble create val pl")
+	fake.send("chan", "alice", "!gamble vote val")
+	fake.send("chan", "bob", "!gamble vote val")
+	fake.send("chan", "carol", "!gamble vote pl")
+	fake.send("chan", "admin", "!gamble reroll")
+	fake.send("chan", "admin", "!gamble close")
+	fake.Said()
+	fake.Whispered()
+
+	fake.send("chan", "admin", "!gamble roll val")
+	first := strings.TrimPrefix(fake.Said()[0], "@admin  : And... The winner is... ")
+	second := map[string]string{"alice": "bob", "bob": "alice"}[first]
+	fake.Whispered()
+
+	fake.send("chan", "carol", "!gamble claim")
+	assert.Equal(t, []string{"@carol  : you have no prize to claim"}, fake.Said())
+
+	// the winner is replaced by another voter of the same team
+	fake.send("chan", "admin", "!gamble reroll")
+	assert.Equal(t, []string{fmt.Sprintf("@admin  : %s forfeited the prize ! And... The new winner is... %s", first, second)}, fake.Said())
+	assert.Equal(t, []sentMessage{
+		{To: "admin", Message: fmt.Sprintf("Psstt, selected winner is : %s (sent on 2020-04-02)", second)},
+		{To: second, Message: "Congrat's ! You're the winner ! Contact the streamer to get your reward ! (sent on 2020-04-02)"},
+	}, fake.Whispered())
+	assert.Equal(t, []Draw{{Team: "val"}, {Team: "val"}}, g.CurrentVote.Draws)
+
+	fake.send("chan", "admin", "!gamble reroll nope")
+	assert.Equal(t, []string{"@admin  : nope is not a winner of this vote"}, fake.Said())
+	fake.send("chan", "admin", "!gamble reroll @"+first)
+	assert.Equal(t, []string{fmt.Sprintf("@admin  : %s already forfeited and was replaced", first)}, fake.Said())
+
+	fake.send("chan", "admin", "!gamble claim")
+	fake.send("chan", first, "!gamble claim")
+	fake.send("chan", second, "!gamble claim")
+	fake.send("chan", second, "!gamble claim")
+	assert.Equal(t, []string{
+		"@admin  : you have no prize to claim",
+		fmt.Sprintf("@%s  : you have no prize to claim", first),
+		fmt.Sprintf("@%s  : your prize is claimed, the streamer will contact you", second),
+		fmt.Sprintf("@%s  : you already claimed your prize", second),
+	}, fake.Said())
+
+	fake.send("chan", "admin", "!gamble reroll "+second)
+	fake.send("chan", "admin", "!gamble reroll")
+	fake.send("chan", "admin", "!gamble winners")
+	assert.Equal(t, []string{
+		fmt.Sprintf("@admin  : %s already claimed the prize", second),
+		"@admin  : There is no winner left to reroll",
+		fmt.Sprintf("@admin  : Ordered list of winners for this vote : %s (forfeit) - %s (claimed)", first, second),
+	}, fake.Said())
+
+	// forfeited prizes are not won
+	l := NewLeaderboard([]*Vote{g.CurrentVote})
+	assert.Equal(t, 0, l.Players["id-"+first].Wins)
+	assert.Equal(t, 1, l.Players["id-"+second].Wins)
+}
+
+func TestClaimTimeout(t *testing.T) {
+	conf := testConf()
+	conf.Rolls.ClaimTimeout = time.Minute
+	g, fake := newTestGambling(t, conf)
+
+	fake.send("chan", "admin", "!gamble create val pl")
+	fake.send("chan", "alice", "!gamble vote val")
+	fake.send("chan", "admin", "!gamble close")
+	fake.Said()
+	fake.Whispered()
+
+	// deadlines follow the clock driving timers
+	clockOf(g).Advance(time.Hour)
+	fake.send("chan", "admin", "!gamble roll val")
+	assert.Equal(t, []string{"@admin  : And... The winner is... alice ! Type '!gamble claim' within 1m0s to get your reward"}, fake.Said())
+	assert.Equal(t, clockOf(g).Now().Add(time.Minute), g.CurrentVote.Claims["id-alice"].Deadline)
+	assert.Equal(t, sentMessage{To: "alice", Message: "Congrat's ! You're the winner ! Type '!gamble claim' in the chat within 1m0s to get your reward ! (sent on 2020-04-02)"}, fake.Whispered()[1])
+
+	clockOf(g).Advance(time.Minute)
+	assert.Equal(t, []string{"@admin  : alice did not claim the prize in time, use '!gamble reroll alice' to select another winner"}, waitSaid(t, fake, 1))
+
+	fake.send("chan", "alice", "!gamble claim")
+	assert.Equal(t, []string{"@alice  : you have no prize to claim"}, fake.Said())
+
+	// nobody can replace alice yet, the winner can still be replaced later
+	fake.send("chan", "admin", "!gamble reroll")
+	assert.Equal(t, []string{"@admin  : alice forfeited the prize. Sorry not enough candidates to roll a winner in team val"}, fake.Said())
+	assert.False(t, g.CurrentVote.Claims["id-alice"].Replaced)
+	assert.Equal(t, claimForfeit, g.CurrentVote.claimStatus("id-alice"))
+}
@@ -0,0 +1,69 @@
+package app
+
+import (
+	"sync"
+	"time"
+)
+
+// fakeClock is a Clock only moving forward when told to
+type fakeClock struct {
+	mutex   sync.Mutex
+	now     time.Time
+	waiters []fakeWaiter
+}
+
+// fakeWaiter is a chan waiting for a date
+type fakeWaiter struct {
+	at time.Time
+	c  chan time.Time
+}
+
+// Ensure the fake implements Clock
+var _ Clock = (*fakeClock)(nil)
+
+// clockOf returns the fake clock driving the timers of a Gambling instance
+func clockOf(g *Gambling) *fakeClock {
+	return g.Scheduler.clock.(*fakeClock)
+}
+
+func newFakeClock(now time.Time) *fakeClock {
+	return &fakeClock{now: now}
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.now
+}
+
+func (c *fakeClock) At(t time.Time) <-chan time.Time {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	w := fakeWaiter{at: t, c: make(chan time.Time, 1)}
+	if !t.After(c.now) {
+		w.c <- c.now
+		return w.c
+	}
+
+	c.waiters = append(c.waiters, w)
+	return w.c
+}
+
+// Advance moves the clock forward, firing all reached waiters
+func (c *fakeClock) Advance(d time.Duration) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.now = c.now.Add(d)
+
+	var waiting []fakeWaiter
+	for _, w := range c.waiters {
+		if w.at.After(c.now) {
+			waiting = append(waiting, w)
+			continue
+		}
+		w.c <- c.now
+	}
+	c.waiters = waiting
+}
@@ -2,6 +2,9 @@ package app
 
 import (
 	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"time"
 
 	"github.com/apex/log"
 
@@ -26,12 +29,207 @@ type Stats struct {
 	Dir string
 }
 
+// Storage is a structure containing config related to vote persistence
+type Storage struct {
+	// Base dir path used to store current vote, persistence is disabled if empty
+	Dir string
+}
+
+// Timer is a structure containing config related to timed votes
+type Timer struct {
+	// Remaining durations triggering a reminder in the channel
+	Reminders []time.Duration
+}
+
+// Wallet is a structure containing config related to the virtual currency used to bet
+type Wallet struct {
+	// Balance given to a viewer seen for the first time
+	Initial int64
+	// Name of the currency
+	Currency string
+}
+
+// Resolve is a structure containing config related to vote resolution
+type Resolve struct {
+	// Maximum number of correct voters listed in the channel, no limit if 0
+	MaxListed int
+	// Number of closest guesses announced when a numeric vote is resolved, 1 if 0
+	Closest int
+}
+
+// Rolls is a structure containing config related to winner draws
+type Rolls struct {
+	// Draw winners from a seed whose hash is published on close, and revealed once winners are rolled
+	Fair bool
+	// Time given to winners to type the claim command, forfeited otherwise, no limit if 0
+	ClaimTimeout time.Duration
+}
+
+// Prizes is a structure containing config related to prizes given to winners
+type Prizes struct {
+	// Catalogue of prize codes, YAML (codes by prize) or CSV (prize,code records)
+	File string
+}
+
+// Messages is a structure containing config related to messages sent by the bot
+type Messages struct {
+	// Locale of messages, read from <dir>/<locale>.yml, default messages are used if empty
+	Locale string
+	// Dir path containing locale files
+	Dir string
+	// Templates of messages, by name, override the locale ones
+	Templates map[string]string
+}
+
+// API is a structure containing config related to the HTTP API
+type API struct {
+	// Listen address, the HTTP API is disabled if empty
+	Address string
+	// Bearer token needed to use the HTTP API
+	Token string
+}
+
+// Bucket is a structure containing the number of messages allowed per second, per 30 seconds and per minute,
+// no limit if 0
+type Bucket struct {
+	Second int
+	// Twitch chat limits are counted over 30 seconds
+	HalfMinute int
+	Minute     int
+}
+
+// Limits is a structure containing config related to outbound messages rate limits,
+// shared by all channels since Twitch limits are tied to the bot account
+type Limits struct {
+	// Bot account tier (default, known, verified or moderator), used for buckets left empty
+	Tier string
+	// Messages sent to channels
+	Chat Bucket
+	// Private messages
+	Whisper Bucket
+}
+
+// tiers are default buckets of each bot account tier, based on Twitch documentation
+var tiers = map[string]Limits{
+	// 20 messages per 30 seconds, 3 whispers per second and 100 per minute
+	"default": {Chat: Bucket{Second: 1, HalfMinute: 20}, Whisper: Bucket{Second: 3, Minute: 100}},
+	// known bots have higher whisper limits
+	"known": {Chat: Bucket{Second: 1, HalfMinute: 20}, Whisper: Bucket{Second: 10, Minute: 200}},
+	// 7500 messages per 30 seconds
+	"verified": {Chat: Bucket{Second: 250, HalfMinute: 7500}, Whisper: Bucket{Second: 20, Minute: 1200}},
+	// moderators of the channel, 100 messages per 30 seconds
+	"moderator": {Chat: Bucket{Second: 3, HalfMinute: 100}, Whisper: Bucket{Second: 3, Minute: 100}},
+}
+
+// withTier fills empty buckets using the tier defaults
+func (l Limits) withTier() Limits {
+	if l.Tier == "" {
+		l.Tier = "default"
+	}
+
+	tier, ok := tiers[l.Tier]
+	if !ok {
+		log.WithField("tier", l.Tier).Warn("Unknown bot tier in limits, using default")
+		tier = tiers["default"]
+	}
+
+	if l.Chat.Second == 0 {
+		l.Chat.Second = tier.Chat.Second
+	}
+	if l.Chat.HalfMinute == 0 {
+		l.Chat.HalfMinute = tier.Chat.HalfMinute
+	}
+	if l.Chat.Minute == 0 {
+		l.Chat.Minute = tier.Chat.Minute
+	}
+	if l.Whisper.Second == 0 {
+		l.Whisper.Second = tier.Whisper.Second
+	}
+	if l.Whisper.HalfMinute == 0 {
+		l.Whisper.HalfMinute = tier.Whisper.HalfMinute
+	}
+	if l.Whisper.Minute == 0 {
+		l.Whisper.Minute = tier.Whisper.Minute
+	}
+
+	return l
+}
+
+// Permissions is a structure containing config related to commands access, using Twitch badges
+// (broadcaster, moderator, vip, subscriber, ...) as roles
+type Permissions struct {
+	// Roles allowed to run admin commands, on top of admins
+	Roles []string
+	// Users never allowed to run any command
+	Deny []string
+	// Twitch user IDs never allowed to run any command
+	DenyIDs []string
+	// Roles allowed to run a command, by command, overrides defaults
+	// the "everyone" role matches all users
+	Commands map[string][]string
+}
+
+// Eligibility is a structure containing rules deciding who can vote (and bet)
+// follower age and account age are not supported, they are not known from chat messages
+type Eligibility struct {
+	// Roles (Twitch badges) allowed to vote, everyone if empty and no minimum tier
+	Roles []string
+	// Minimum subscription tier (1, 2 or 3) allowed to vote, on top of roles, disabled if 0
+	MinTier int
+	// Users never allowed to vote, like other bots of the channel
+	Ignore []string
+	// Twitch user IDs never allowed to vote
+	IgnoreIDs []string
+}
+
+// Weights is a structure containing config related to weighted vote counting
+type Weights struct {
+	// Weight of each role (Twitch badge), the "everyone" role sets the weight of other users (1 by default)
+	Roles map[string]float64
+	// Weight of each subscription tier (1, 2 or 3)
+	Tiers map[int]float64
+	// Use weights as tickets when rolling a winner
+	Tickets bool
+}
+
+// ChannelConf is a structure containing config dedicated to a single Twitch channel,
+// empty fields fallback to the global ones
+type ChannelConf struct {
+	Name     string
+	Admins   []string
+	AdminIDs []string
+	Hello    string
+	Prefix   string
+	// Locale of messages sent to this channel
+	Locale string
+}
+
 // Conf is a meta structure containing all nedded configuration for a gambling instance
 type Conf struct {
-	Pastebin PastebinCreds
-	Twitch   TwitchCreds
-	Stats    Stats
-	Admins   []string
+	Pastebin    PastebinCreds
+	Twitch      TwitchCreds
+	Stats       Stats
+	Storage     Storage
+	Timer       Timer
+	Wallet      Wallet
+	Resolve     Resolve
+	Rolls       Rolls
+	Prizes      Prizes
+	Messages    Messages
+	API         API
+	Limits      Limits
+	Permissions Permissions
+	// Rule used by votes, unless a profile is given on creation
+	Eligibility Eligibility
+	// Rules which can be given on creation, by name
+	Profiles map[string]Eligibility
+	// Weights of votes, the highest one of a user applies
+	Weights  Weights
+	Channels []ChannelConf
+	// Admins, by login
+	Admins []string
+	// Admins, by Twitch user ID, which survive a rename
+	AdminIDs []string
 	Hello    string
 	Prefix   string
 	Verified bool
@@ -52,12 +250,102 @@ func (c *Conf) getConf(path string) *Conf {
 	}
 
 	log.WithFields(log.Fields{
-		"Admins":   c.Admins,
-		"Prefix":   c.Prefix,
-		"Verified": c.Verified,
-		"Hello":    c.Hello,
-		"Stats":    c.Stats,
+		"Channels":    c.Channels,
+		"Admins":      c.Admins,
+		"AdminIDs":    c.AdminIDs,
+		"Prefix":      c.Prefix,
+		"Verified":    c.Verified,
+		"Hello":       c.Hello,
+		"Stats":       c.Stats,
+		"Storage":     c.Storage,
+		"Timer":       c.Timer,
+		"Wallet":      c.Wallet,
+		"Resolve":     c.Resolve,
+		"Rolls":       c.Rolls,
+		"Prizes":      c.Prizes,
+		"Messages":    c.Messages.Locale,
+		"API":         c.API.Address,
+		"Limits":      c.Limits,
+		"Permissions": c.Permissions,
+		"Eligibility": c.Eligibility,
+		"Profiles":    c.Profiles,
+		"Weights":     c.Weights,
 	}).Info("Parameters from config file")
 
+	// Warn about unknown commands in permissions
+	for cmd := range c.Permissions.Commands {
+		if _, ok := commands[cmd]; !ok {
+			log.WithField("command", cmd).Warn("Unknown command in permissions")
+		}
+	}
+
+	// Default reminders
+	if c.Timer.Reminders == nil {
+		c.Timer.Reminders = []time.Duration{60 * time.Second, 30 * time.Second, 10 * time.Second}
+	}
+
+	// Profiles are given on creation, lowercased like all commands args
+	profiles := make(map[string]Eligibility)
+	for name, p := range c.Profiles {
+		profiles[strings.ToLower(name)] = p
+	}
+	c.Profiles = profiles
+
+	// Default rate limits
+	c.Limits = c.Limits.withTier()
+
+	// Default wallet
+	if c.Wallet.Initial == 0 {
+		c.Wallet.Initial = 1000
+	}
+	if c.Wallet.Currency == "" {
+		c.Wallet.Currency = "points"
+	}
+
 	return c
 }
+
+// channelConfs returns a config for each channel served by the bot
+func (c Conf) channelConfs() []Conf {
+
+	// no channel list, legacy single channel config
+	if len(c.Channels) == 0 {
+		conf := c
+		conf.Twitch.Channel = strings.ToLower(c.Twitch.Channel)
+		return []Conf{conf}
+	}
+
+	var confs []Conf
+
+	for _, ch := range c.Channels {
+		conf := c
+		conf.Channels = nil
+		conf.Twitch.Channel = strings.ToLower(ch.Name)
+
+		if len(ch.Admins) > 0 || len(ch.AdminIDs) > 0 {
+			conf.Admins = ch.Admins
+			conf.AdminIDs = ch.AdminIDs
+		}
+		if ch.Hello != "" {
+			conf.Hello = ch.Hello
+		}
+		if ch.Prefix != "" {
+			conf.Prefix = ch.Prefix
+		}
+		if ch.Locale != "" {
+			conf.Messages.Locale = ch.Locale
+		}
+
+		// each channel gets its own directories, so files never collide
+		if c.Stats.Dir != "" {
+			conf.Stats.Dir = filepath.Join(c.Stats.Dir, conf.Twitch.Channel)
+		}
+		if c.Storage.Dir != "" {
+			conf.Storage.Dir = filepath.Join(c.Storage.Dir, conf.Twitch.Channel)
+		}
+
+		confs = append(confs, conf)
+	}
+
+	return confs
+}
@@ -2,6 +2,7 @@ package app
 
 import (
 	"testing"
+	"time"
 
 	"github.com/tj/assert"
 )
@@ -18,4 +19,75 @@ func TestGetConf(t *testing.T) {
 	assert.Equal(t, expectedToken, c.Twitch.Oauth)
 
 	assert.Equal(t, expectedAdminLen, len(c.Admins))
+
+	assert.True(t, c.Rolls.Fair)
+	assert.Equal(t, 5*time.Minute, c.Rolls.ClaimTimeout)
+	assert.Equal(t, "/etc/gamble/prizes.yml", c.Prizes.File)
+	assert.Equal(t, "fr", c.Messages.Locale)
+	assert.Equal(t, "/etc/gamble/messages", c.Messages.Dir)
+	assert.Equal(t, map[string]string{"delete": "Vote supprimé !"}, c.Messages.Templates)
+
+	// empty buckets use the tier defaults
+	assert.Equal(t, Limits{
+		Tier:    "known",
+		Chat:    Bucket{Second: 1, HalfMinute: 20},
+		Whisper: Bucket{Second: 10, Minute: 200},
+	}, c.Limits)
+}
+
+func TestLimitsWithTier(t *testing.T) {
+	assert.Equal(t, tiers["default"].Whisper, Limits{}.withTier().Whisper)
+	assert.Equal(t, tiers["default"].Chat, Limits{Tier: "nope"}.withTier().Chat)
+
+	l := Limits{Tier: "verified", Whisper: Bucket{Minute: 600}}.withTier()
+	assert.Equal(t, Bucket{Second: 20, Minute: 600}, l.Whisper)
+	assert.Equal(t, tiers["verified"].Chat, l.Chat)
+}
+
+func TestChannelConfsLegacy(t *testing.T) {
+	c := Conf{
+		Twitch: TwitchCreds{Channel: "Chan"},
+		Admins: []string{"admin"},
+		Prefix: "!gamble",
+		Stats:  Stats{Dir: "/stats"},
+	}
+
+	confs := c.channelConfs()
+
+	assert.Equal(t, 1, len(confs))
+	assert.Equal(t, "chan", confs[0].Twitch.Channel)
+	assert.Equal(t, "/stats", confs[0].Stats.Dir)
+}
+
+func TestChannelConfs(t *testing.T) {
+	c := Conf{
+		Admins:  []string{"admin"},
+		Prefix:  "!gamble",
+		Hello:   "Hello",
+		Stats:   Stats{Dir: "/stats"},
+		Storage: Storage{Dir: "/storage"},
+		Channels: []ChannelConf{
+			{Name: "First"},
+			{Name: "second", Admins: []string{"streamer"}, Prefix: "!bet", Hello: "Salut", Locale: "fr"},
+		},
+	}
+
+	confs := c.channelConfs()
+
+	assert.Equal(t, 2, len(confs))
+
+	assert.Equal(t, "first", confs[0].Twitch.Channel)
+	assert.Equal(t, []string{"admin"}, confs[0].Admins)
+	assert.Equal(t, "!gamble", confs[0].Prefix)
+	assert.Equal(t, "Hello", confs[0].Hello)
+	assert.Equal(t, "/stats/first", confs[0].Stats.Dir)
+	assert.Equal(t, "/storage/first", confs[0].Storage.Dir)
+	assert.Equal(t, "", confs[0].Messages.Locale)
+
+	assert.Equal(t, "second", confs[1].Twitch.Channel)
+	assert.Equal(t, []string{"streamer"}, confs[1].Admins)
+	assert.Equal(t, "!bet", confs[1].Prefix)
+	assert.Equal(t, "Salut", confs[1].Hello)
+	assert.Equal(t, "fr", confs[1].Messages.Locale)
+	assert.Equal(t, "/storage/second", confs[1].Storage.Dir)
 }
@@ -0,0 +1,200 @@
+package app
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"io"
+	mrand "math/rand"
+	"sort"
+	"strconv"
+)
+
+// Draw is a structure containing how a winner was rolled, used to verify draws
+type Draw struct {
+	// Team the winner was rolled in, allTeams for every voter
+	Team string
+	// Closest guesses of a resolved numeric vote were the candidates
+	Closest bool `json:",omitempty"`
+	// Candidates had as many tickets as their weight
+	Tickets bool `json:",omitempty"`
+}
+
+// cryptoSource is a math/rand source reading from the crypto-secure random generator
+type cryptoSource struct{}
+
+// Seed does nothing, a crypto-secure source can not be seeded
+func (cryptoSource) Seed(int64) {}
+
+// Int63 returns a non-negative random int64
+func (s cryptoSource) Int63() int64 {
+	return int64(s.Uint64() >> 1)
+}
+
+// Uint64 returns a random uint64
+func (cryptoSource) Uint64() uint64 {
+	var b [8]byte
+
+	_, err := rand.Read(b[:])
+	if err != nil {
+		panic(fmt.Sprintf("Error reading random bytes : %s", err))
+	}
+
+	return binary.BigEndian.Uint64(b[:])
+}
+
+// newSeed is used to generate the random seed of a provably fair vote
+func newSeed() string {
+	b := make([]byte, 32)
+
+	_, err := rand.Read(b)
+	if err != nil {
+		panic(fmt.Sprintf("Error reading random bytes : %s", err))
+	}
+
+	return hex.EncodeToString(b)
+}
+
+// hashSeed returns the SHA-256 hash of a seed, published before winners are rolled
+func hashSeed(seed string) string {
+	sum := sha256.Sum256([]byte(seed))
+
+	return hex.EncodeToString(sum[:])
+}
+
+// seededRand returns the generator used to draw the winner at index i of a provably fair vote,
+// each draw has its own generator so winners do not depend on how many were rolled at once
+func seededRand(seed string, i int) *mrand.Rand {
+	sum := sha256.Sum256([]byte(seed + ":" + strconv.Itoa(i)))
+
+	return mrand.New(mrand.NewSource(int64(binary.BigEndian.Uint64(sum[:8]))))
+}
+
+// fair returns true if winners of the vote are drawn from its committed seed
+func (v *Vote) fair() bool {
+	return v.SeedHash != ""
+}
+
+// generator returns the generator used to draw the next winner of the vote
+func (v *Vote) generator() *mrand.Rand {
+	if v.fair() {
+		return seededRand(v.Seed, len(v.Winners))
+	}
+
+	return mrand.New(cryptoSource{})
+}
+
+// candidates returns voters who can be drawn, sorted by ID so a seed always gives the same winner
+// winners already drawn are excluded
+func (v *Vote) candidates(d Draw, winners []string) []string {
+	drawn := make(map[string]bool)
+	for _, w := range winners {
+		drawn[w] = true
+	}
+
+	var res []string
+
+	for user := range v.Votes {
+		if drawn[user] {
+			continue
+		}
+
+		switch {
+		case d.Team == allTeams:
+			res = append(res, user)
+		case d.Closest:
+			if v.isClosest(user) {
+				res = append(res, user)
+			}
+		default:
+			for _, c := range v.counted(user) {
+				if c == d.Team {
+					res = append(res, user)
+					break
+				}
+			}
+		}
+	}
+
+	sort.Strings(res)
+
+	return res
+}
+
+// draw is used to pick a candidate using a generator
+func (v *Vote) draw(r *mrand.Rand, d Draw, candidates []string) string {
+	// voters with a higher weight get more tickets
+	if d.Tickets {
+		return drawTicket(r, v, candidates)
+	}
+
+	return candidates[r.Intn(len(candidates))]
+}
+
+// commitSeed is used to generate the seed of a provably fair vote when it is closed, only its hash is published
+func (g *Gambling) commitSeed() {
+	if !g.Config.Rolls.Fair {
+		return
+	}
+
+	g.CurrentVote.Seed = newSeed()
+	g.CurrentVote.SeedHash = hashSeed(g.CurrentVote.Seed)
+}
+
+// revealSeed is used to publish the seed of a provably fair vote once winners are rolled
+func (g *Gambling) revealSeed() {
+	if !g.CurrentVote.fair() {
+		return
+	}
+
+	g.announce(g.text("roll.seed", vars{"Vote": g.CurrentVote.ID, "Seed": g.CurrentVote.Seed}))
+}
+
+// VerifyDraws writes winners of a past vote of a channel drawn again from its seed,
+// an error is returned if the seed does not match the published hash or if a winner differs
+// the archived seed is used if seed is empty
+func VerifyDraws(w io.Writer, confPath string, channel string, id string, seed string) error {
+	h, err := openHistory(confPath, channel)
+	if err != nil {
+		return err
+	}
+
+	v := h.Find(id)
+	if v == nil {
+		return fmt.Errorf("Unknown vote %s", id)
+	}
+
+	if !v.fair() {
+		return fmt.Errorf("Winners of vote %s were not drawn from a committed seed", id)
+	}
+	if len(v.Draws) != len(v.Winners) {
+		return fmt.Errorf("Draws of vote %s were not recorded", id)
+	}
+
+	if seed == "" {
+		seed = v.Seed
+	}
+	if hashSeed(seed) != v.SeedHash {
+		return fmt.Errorf("Seed does not match the published hash %s", v.SeedHash)
+	}
+
+	for i, d := range v.Draws {
+		candidates := v.candidates(d, v.Winners[:i])
+		if len(candidates) == 0 {
+			return fmt.Errorf("Winner %d : no candidate in team %s", i+1, d.Team)
+		}
+
+		winner := v.draw(seededRand(seed, i), d, candidates)
+		if winner != v.Winners[i] {
+			return fmt.Errorf("Winner %d : %s drawn in team %s but %s was announced", i+1, v.name(winner), d.Team, v.name(v.Winners[i]))
+		}
+
+		fmt.Fprintf(w, "%d. %s (%s, %d candidates)\n", i+1, v.name(winner), d.Team, len(candidates))
+	}
+
+	fmt.Fprintf(w, "All %d winners of vote %s match seed %s\n", len(v.Winners), id, seed)
+
+	return nil
+}
@@ -0,0 +1,102 @@
+package app
+
+import (
+	"bytes"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCandidates(t *testing.T) {
+	v := &Vote{
+		Possibilities: []string{"val", "pl"},
+		Votes:         map[string]string{"c": "val", "a": "val", "b": "pl", "d": "val"},
+	}
+
+	// sorted by ID, whatever the map order
+	assert.Equal(t, []string{"a", "c", "d"}, v.candidates(Draw{Team: "val"}, nil))
+	assert.Equal(t, []string{"a", "b", "d"}, v.candidates(Draw{Team: allTeams}, []string{"c"}))
+	assert.Empty(t, v.candidates(Draw{Team: "pl"}, []string{"b"}))
+
+	// the same seed always draws the same winner
+	d := Draw{Team: allTeams}
+	first := v.draw(seededRand("seed", 0), d, v.candidates(d, nil))
+	for i := 0; i < 10; i++ {
+		assert.Equal(t, first, v.draw(seededRand("seed", 0), d, v.candidates(d, nil)))
+	}
+}
+
+func TestFairRoll(t *testing.T) {
+	dir := t.TempDir()
+	conf := testConf()
+	conf.Storage.Dir = dir
+	conf.Rolls.Fair = true
+	g, fake := newTestGambling(t, conf)
+
+	fake.send("chan", "admin", "!gamble create val pl")
+	fake.Said()
+	for _, u := range []string{"alice", "bob", "carol", "dave", "erin"} {
+		fake.send("chan", u, "!gamble vote val")
+	}
+	fake.send("chan", "admin", "!gamble close")
+	fake.Whispered()
+
+	// only the hash is published on close
+	seed := g.CurrentVote.Seed
+	assert.Len(t, seed, 64)
+	assert.Equal(t, hashSeed(seed), g.CurrentVote.SeedHash)
+	said := fake.Said()
+	assert.Len(t, said, 2)
+	assert.Equal(t, "Winners will be drawn using a seed whose SHA-256 hash is "+g.CurrentVote.SeedHash, said[1])
+	assert.Equal(t, "", g.state().Seed)
+
+	// the seed is revealed once winners are rolled
+	fake.send("chan", "admin", "!gamble roll val 2")
+	fake.send("chan", "admin", "!gamble roll all")
+	said = fake.Said()
+	assert.Len(t, said, 4)
+	assert.Equal(t, "Draw seed of vote "+g.CurrentVote.ID+" : "+seed, said[1])
+	assert.Equal(t, seed, g.state().Seed)
+	assert.Len(t, g.CurrentVote.Draws, 3)
+
+	// winners are drawn again from the archived vote
+	path := filepath.Join(dir, "config.yml")
+	assert.Nil(t, ioutil.WriteFile(path, []byte("twitch:\n  channel: chan\nstorage:\n  dir: "+dir+"\n"), 0644))
+
+	var out bytes.Buffer
+	assert.Nil(t, VerifyDraws(&out, path, "", g.CurrentVote.ID, seed))
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	assert.Len(t, lines, 4)
+	assert.Equal(t, "1. "+g.CurrentVote.name(g.CurrentVote.Winners[0])+" (val, 5 candidates)", lines[0])
+	assert.Equal(t, "3. "+g.CurrentVote.name(g.CurrentVote.Winners[2])+" (all, 3 candidates)", lines[2])
+
+	// the archived seed is used by default
+	assert.Nil(t, VerifyDraws(&out, path, "", g.CurrentVote.ID, ""))
+
+	err := VerifyDraws(&out, path, "", g.CurrentVote.ID, "nope")
+	assert.EqualError(t, err, "Seed does not match the published hash "+g.CurrentVote.SeedHash)
+
+	// a tampered winner is detected
+	archived := g.History.Find(g.CurrentVote.ID)
+	archived.Winners[0], archived.Winners[1] = archived.Winners[1], archived.Winners[0]
+	assert.Nil(t, g.History.save())
+	assert.NotNil(t, VerifyDraws(&out, path, "", g.CurrentVote.ID, ""))
+}
+
+func TestUnfairRoll(t *testing.T) {
+	g, fake := newTestGambling(t, testConf())
+
+	fake.send("chan", "admin", "!gamble create val pl")
+	fake.Said()
+	fake.send("chan", "alice", "!gamble vote val")
+	fake.send("chan", "admin", "!gamble close")
+	fake.send("chan", "admin", "!gamble roll val")
+
+	// no seed is committed nor revealed
+	assert.Len(t, fake.Said(), 2)
+	assert.Equal(t, "", g.CurrentVote.SeedHash)
+	assert.Equal(t, []Draw{{Team: "val"}}, g.CurrentVote.Draws)
+}
@@ -0,0 +1,108 @@
+package app
+
+import (
+	"strings"
+
+	"github.com/apex/log"
+	twitch "github.com/gempir/go-twitch-irc/v2"
+)
+
+// subTier returns the subscription tier of a user (1, 2 or 3), 0 if the user is not a subscriber
+// the subscriber badge version is the number of months, prefixed by 2 or 3 for higher tiers (2012 is tier 2, 12 months)
+func subTier(user twitch.User) int {
+	if v, ok := user.Badges["subscriber"]; ok {
+		if v >= 1000 {
+			return v / 1000
+		}
+		return 1
+	}
+
+	// founders have their own badge instead of the subscriber one
+	if _, ok := user.Badges["founder"]; ok {
+		return 1
+	}
+
+	return 0
+}
+
+// restricted returns true if the rule does not let everyone vote
+func (e *Eligibility) restricted() bool {
+	return e != nil && (len(e.Roles) > 0 || e.MinTier > 0)
+}
+
+// audience returns who can vote, empty if everyone can
+func (e *Eligibility) audience(c *Catalogue) string {
+	if !e.restricted() {
+		return ""
+	}
+
+	var parts []string
+	if len(e.Roles) > 0 {
+		parts = append(parts, c.render("audience.roles", vars{"Roles": e.Roles}))
+	}
+	if e.MinTier > 1 {
+		parts = append(parts, c.render("audience.tier", vars{"Tier": e.MinTier}))
+	} else if e.MinTier == 1 {
+		parts = append(parts, c.render("audience.subs", nil))
+	}
+
+	return c.render("audience", vars{"Parts": parts})
+}
+
+// check returns why a user can not vote, empty if the user is eligible
+//   - ignored users (other bots of the channel) can never vote
+//   - if roles or a minimum tier are set, users need one of the roles, or a subscription of at least this tier
+func (e *Eligibility) check(c *Catalogue, user twitch.User) string {
+	if e == nil {
+		return ""
+	}
+
+	if checkIdentity(user, e.Ignore, e.IgnoreIDs) {
+		return c.render("reject.ignored", nil)
+	}
+
+	if !e.restricted() {
+		return ""
+	}
+
+	if len(e.Roles) > 0 && checkRoles(user, e.Roles) {
+		return ""
+	}
+	if e.MinTier > 0 && subTier(user) >= e.MinTier {
+		return ""
+	}
+
+	return c.render("reject.reserved", vars{"Audience": e.audience(c)})
+}
+
+// extractEligibility is used to extract an optional eligibility profile from create args,
+// the default rule is used if args do not start with a profile name
+func extractEligibility(args []string, conf Conf) (string, *Eligibility, []string) {
+	if len(args) > 0 {
+		name := strings.ToLower(args[0])
+		if p, ok := conf.Profiles[name]; ok {
+			return name, &p, args[1:]
+		}
+	}
+
+	e := conf.Eligibility
+	return "", &e, args
+}
+
+// eligible is used to check if a user can vote in the current vote, the user gets the reason otherwise
+func (g *Gambling) eligible(user twitch.User) bool {
+	reason := g.CurrentVote.Eligibility.check(g.Messages, user)
+	if reason == "" {
+		return true
+	}
+
+	log.WithFields(log.Fields{
+		"user":   user.Name,
+		"id":     user.ID,
+		"reason": reason,
+	}).Info("Vote rejected")
+
+	g.ack(user.Name, g.text("vote.rejected", vars{"Reason": reason}))
+
+	return false
+}
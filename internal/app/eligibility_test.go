@@ -0,0 +1,83 @@
+package app
+
+import (
+	"testing"
+
+	twitch "github.com/gempir/go-twitch-irc/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSubTier(t *testing.T) {
+	assert.Equal(t, 0, subTier(twitch.User{}))
+	assert.Equal(t, 1, subTier(twitch.User{Badges: map[string]int{"subscriber": 12}}))
+	assert.Equal(t, 2, subTier(twitch.User{Badges: map[string]int{"subscriber": 2003}}))
+	assert.Equal(t, 3, subTier(twitch.User{Badges: map[string]int{"subscriber": 3000}}))
+	assert.Equal(t, 1, subTier(twitch.User{Badges: map[string]int{"founder": 0}}))
+}
+
+func TestEligibilityCheck(t *testing.T) {
+	var everyone *Eligibility
+	assert.Equal(t, "", everyone.check(defaultCatalogue, twitch.User{Name: "alice"}))
+
+	e := &Eligibility{Roles: []string{"vip", "moderator"}, MinTier: 2, Ignore: []string{"nightbot"}, IgnoreIDs: []string{"42"}}
+
+	assert.Equal(t, "you are excluded from votes in this channel", e.check(defaultCatalogue, twitch.User{Name: "nightbot", Badges: map[string]int{"moderator": 1}}))
+	assert.Equal(t, "you are excluded from votes in this channel", e.check(defaultCatalogue, twitch.User{ID: "42", Name: "streamelements"}))
+
+	assert.Equal(t, "", e.check(defaultCatalogue, twitch.User{Name: "alice", Badges: map[string]int{"vip": 1}}))
+	assert.Equal(t, "", e.check(defaultCatalogue, twitch.User{Name: "bob", Badges: map[string]int{"subscriber": 2006}}))
+	assert.Equal(t, "this vote is reserved to vip, moderator viewers and tier 2 subscribers and above",
+		e.check(defaultCatalogue, twitch.User{Name: "carol", Badges: map[string]int{"subscriber": 6}}))
+
+	// ignore list only
+	e = &Eligibility{Ignore: []string{"nightbot"}}
+	assert.Equal(t, "", e.check(defaultCatalogue, twitch.User{Name: "carol"}))
+	assert.Equal(t, "", e.audience(defaultCatalogue))
+}
+
+func TestExtractEligibility(t *testing.T) {
+	conf := testConf()
+	conf.Eligibility = Eligibility{Ignore: []string{"nightbot"}}
+	conf.Profiles = map[string]Eligibility{"subs": {MinTier: 1}}
+
+	name, e, args := extractEligibility([]string{"Subs", "val", "pl"}, conf)
+	assert.Equal(t, "subs", name)
+	assert.Equal(t, 1, e.MinTier)
+	assert.Equal(t, []string{"val", "pl"}, args)
+
+	name, e, args = extractEligibility([]string{"val", "pl"}, conf)
+	assert.Equal(t, "", name)
+	assert.Equal(t, []string{"nightbot"}, e.Ignore)
+	assert.Equal(t, []string{"val", "pl"}, args)
+}
+
+func TestEligibleVote(t *testing.T) {
+	conf := testConf()
+	conf.Eligibility = Eligibility{Ignore: []string{"nightbot"}}
+	conf.Profiles = map[string]Eligibility{"subs": {MinTier: 1, Ignore: []string{"nightbot"}}}
+	g, fake := newTestGambling(t, conf)
+
+	fake.send("chan", "admin", "!gamble create 2m subs val pl")
+	assert.Equal(t, []string{"There is a new vote! You can vote with '!gamble vote <vote> (choices are : val or pl)', reserved to subscribers, it will close automatically in 2m0s"}, fake.Said())
+	assert.Equal(t, "subs", g.CurrentVote.Profile)
+
+	fake.sendAs("chan", twitch.User{ID: "1", Name: "alice", Badges: map[string]int{"subscriber": 3}}, "!gamble vote val")
+	fake.send("chan", "bob", "!gamble vote pl")
+	fake.send("chan", "bob", "!gamble bet pl 10")
+	fake.sendAs("chan", twitch.User{ID: "2", Name: "nightbot", Badges: map[string]int{"subscriber": 3}}, "!gamble vote pl")
+
+	assert.Equal(t, []sentMessage{
+		{To: "alice", Message: "For your information, I correctly handled your vote for val (sent on 2020-04-02)"},
+		{To: "bob", Message: "Sorry but this vote is reserved to subscribers (sent on 2020-04-02)"},
+		{To: "bob", Message: "Sorry but this vote is reserved to subscribers (sent on 2020-04-02)"},
+		{To: "nightbot", Message: "Sorry but you are excluded from votes in this channel (sent on 2020-04-02)"},
+	}, fake.Whispered())
+	assert.Equal(t, map[string]string{"1": "val"}, g.CurrentVote.Votes)
+
+	// the default rule only ignores bots
+	fake.send("chan", "admin", "!gamble close")
+	fake.send("chan", "admin", "!gamble create val pl")
+	fake.send("chan", "bob", "!gamble vote pl")
+	fake.send("chan", "nightbot", "!gamble vote pl")
+	assert.Equal(t, map[string]string{"id-bob": "pl"}, g.CurrentVote.Votes)
+}
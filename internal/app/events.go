@@ -0,0 +1,82 @@
+package app
+
+import (
+	"sync"
+
+	"github.com/apex/log"
+)
+
+// Size of each subscriber buffered chan
+const subscriberBufferSize = 16
+
+// VoteEvent is a structure describing a change of the current vote
+type VoteEvent struct {
+	// Kind of change (create, vote, close, roll, reset, delete, ...)
+	Type  string    `json:"type"`
+	State VoteState `json:"state"`
+}
+
+// Hub is used to broadcast vote events to subscribers
+type Hub struct {
+	mutex       sync.Mutex
+	subscribers map[chan VoteEvent]bool
+}
+
+// NewHub is used to init a Hub struct
+func NewHub() *Hub {
+	return &Hub{
+		subscribers: make(map[chan VoteEvent]bool),
+	}
+}
+
+// Subscribe returns a chan receiving all events published from now on
+func (h *Hub) Subscribe() chan VoteEvent {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	sub := make(chan VoteEvent, subscriberBufferSize)
+	h.subscribers[sub] = true
+
+	return sub
+}
+
+// Unsubscribe stops sending events to a subscriber
+func (h *Hub) Unsubscribe(sub chan VoteEvent) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	delete(h.subscribers, sub)
+}
+
+// Publish sends an event to all subscribers, a slow subscriber misses events instead of blocking the bot
+func (h *Hub) Publish(event VoteEvent) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	for sub := range h.subscribers {
+		select {
+		case sub <- event:
+		default:
+			log.WithField("type", event.Type).Warn("Subscriber too slow, vote event dropped")
+		}
+	}
+}
+
+// Subscribed tells whether the hub has at least one subscriber
+func (h *Hub) Subscribed() bool {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	return len(h.subscribers) > 0
+}
+
+// publish is used to broadcast the current vote state
+func (g *Gambling) publish(kind string) {
+
+	// the state is not built for nobody
+	if !g.Events.Subscribed() {
+		return
+	}
+
+	g.Events.Publish(VoteEvent{Type: kind, State: g.state()})
+}
@@ -1,103 +1,168 @@
 package app
 
 import (
+	crand "crypto/rand"
+	"encoding/hex"
 	"errors"
 	"fmt"
-	"math/rand"
+	"strconv"
 	"strings"
 	"time"
 
-	"context"
-
-	"golang.org/x/time/rate"
-
 	"github.com/apex/log"
 	twitch "github.com/gempir/go-twitch-irc/v2"
 )
 
-// Size ack buffered chan
-const bufferSize = 500
+// now returns the current time, overridden in tests
+var now = time.Now
 
 // Vote is a structure handling all voting params and status
 type Vote struct {
+	// Unique identifier, generated on creation
+	ID            string
 	IsOpen        bool
 	Possibilities []string
-	Votes         map[string]string
-	Acks          Acks
-	Winners       []string
+	// Vote mode (single, multi or ranked), empty for single
+	Mode string `json:",omitempty"`
+	// Maximum number of choices of a multi-select vote, no limit if 0
+	MaxChoices int `json:",omitempty"`
+	// Name of the eligibility profile given on creation, empty for the default rule
+	Profile string `json:",omitempty"`
+	// Who can vote, everyone if nil
+	Eligibility *Eligibility `json:",omitempty"`
+	// Bounds of a numeric vote, no bounds if nil
+	Min *float64 `json:",omitempty"`
+	Max *float64 `json:",omitempty"`
+	// Users are identified by their Twitch user ID, votes stored by older versions by their login
+	// First choice, by user
+	Votes map[string]string
+	// All choices in order, by user, for multi-select and ranked-choice votes
+	Ballots map[string][]string `json:",omitempty"`
+	// Names, by user, used in chat messages
+	Voters  map[string]Voter `json:",omitempty"`
+	Winners []string
+	// Bets, by user
+	Bets map[string]Bet
+	// Prize given to winners, empty if none
+	Prize string `json:",omitempty"`
+	// Result of the vote, empty until resolved
+	Outcome string
+	// Closest guesses of a numeric vote, set once resolved
+	Closest []string `json:",omitempty"`
+	// How each winner was rolled, in the same order as winners
+	Draws []Draw `json:",omitempty"`
+	// Claim of each winner, by user
+	Claims map[string]Claim `json:",omitempty"`
+	// Hash of the seed used to draw winners, published on close in provably fair mode
+	SeedHash string `json:",omitempty"`
+	// Seed used to draw winners, revealed once winners are rolled
+	Seed string `json:",omitempty"`
+	// Automatic closing date, zero if the vote is not timed
+	Deadline time.Time
+	// Opening and closing dates
+	OpenedAt time.Time
+	ClosedAt time.Time
 }
 
-// Acks is used to store and send ack messages stored if rate limit is reached
-type Acks struct {
-	Buffer chan VoteAck
-	WIP    bool
-	Drop   chan bool
-}
+// newVoteID is used to generate a random vote identifier, long enough to never collide in history
+func newVoteID() string {
+	b := make([]byte, 8)
 
-// NewAcks is used to init a Acks struct
-func NewAcks() Acks {
-	return Acks{
-		Buffer: make(chan VoteAck, bufferSize),
-		WIP:    false,
-		Drop:   make(chan bool),
+	_, err := crand.Read(b)
+	if err != nil {
+		// fallback on current time, still unique enough for a single bot
+		return fmt.Sprintf("%x", now().UnixNano())
 	}
-}
 
-// VoteAck is a struct containing vote acknolegment send later if rate limit is reached
-type VoteAck struct {
-	message  string
-	Username string
+	return hex.EncodeToString(b)
 }
 
-// NewVoteAck is used to init a VoteAck struct
-func NewVoteAck(message string, username string) VoteAck {
-	return VoteAck{
-		message:  message,
-		Username: username,
-	}
-}
-
-// Gambling is a meta structure containing all the stuff needed by a Gambling instance
+// Gambling is a meta structure containing all the stuff needed by a Gambling instance on a channel
 type Gambling struct {
-	// Config from yaml file
+	// Config from yaml file, for this channel
 	Config Conf
-	// Twitch client
-	Twitch *twitch.Client
+	// Chat transport, Twitch client by default
+	Transport ChatTransport
 	// Vote
 	CurrentVote *Vote
-	// whisper rate limiter
-	WhispRL *rate.Limiter
-	// Warning rate limiter
-	WarnRL *rate.Limiter
+	// Vote store, nil if persistence is disabled
+	Store VoteStore
+	// Vote events broadcaster
+	Events *Hub
+	// Acks waiting for whisper rate limit
+	Acks *AckQueue
+	// Last rate limit warning sent to the channel
+	warnedAt time.Time
+	// Virtual currency balances
+	Ledger *Ledger
+	// Completed votes
+	History *History
+	// Prize codes, shared by all channels, nil if there is no catalogue
+	Prizes *Inventory
+	// Messages sent to the channel, in its locale
+	Messages *Catalogue
+	// Logins of admins listed by Twitch user ID, by ID, once seen in the channel
+	adminLogins map[string]string
+	// Is the current vote restored from store ?
+	restored bool
+	// Automatic closing timer, nil if the vote is not timed
+	timer *voteTimer
+	// Actions run by the event loop, owning the vote state
+	actions chan func()
+	// Outbound messages scheduler, shared by all channels
+	Scheduler *Scheduler
 }
 
-// NewGambling func create a new Gambling struct
-func NewGambling(confPath string) *Gambling {
+// NewGambling func create a new Gambling struct for the channel configured in conf
+func NewGambling(conf Conf, transport ChatTransport) *Gambling {
 	// Empty new struct
 	g := new(Gambling)
 
-	// Parse config
-	g.Config.getConf(confPath)
-
-	// Setup twitch client
-	g.Twitch = twitch.NewClient(g.Config.Twitch.Username, g.Config.Twitch.Oauth)
+	// Config and transport
+	g.Config = conf
+	g.Transport = transport
 
-	// Plug function on Twitch events
-	g.twitchOnEventSetup()
+	// Messages templates
+	g.Messages = NewCatalogue(conf)
 
-	// Join Twitch channel
-	g.join()
+	// Start event loop
+	g.actions = make(chan func())
+	go g.loop()
 
 	// init vote
 	g.CurrentVote = new(Vote)
 
-	// setup rate limiter for whispers
-	// 19 times per second, burst set to 1
-	g.WhispRL = rate.NewLimiter(19, 1)
+	// init vote events broadcaster
+	g.Events = NewHub()
 
-	// setup rate limiter for warning messages
-	// One every 15 seconds
-	g.WarnRL = rate.NewLimiter(rate.Every(15*time.Second), 1)
+	// Acks are sent by the scheduler
+	g.Acks = NewAckQueue(bufferSize)
+
+	// setup vote store, if configured
+	if conf.Storage.Dir != "" {
+		store, err := NewFileVoteStore(conf.Storage.Dir)
+		if err != nil {
+			log.WithError(err).Fatalf("Error creating storage directory : %s", conf.Storage.Dir)
+		}
+		g.Store = store
+	}
+
+	// setup virtual currency ledger, persisted with votes
+	ledger, err := NewLedger(conf.Storage.Dir, conf.Wallet.Initial)
+	if err != nil {
+		log.WithError(err).Fatalf("Error loading ledger from : %s", conf.Storage.Dir)
+	}
+	g.Ledger = ledger
+
+	// setup votes history, persisted with votes
+	history, err := NewHistory(conf.Storage.Dir)
+	if err != nil {
+		log.WithError(err).Fatalf("Error loading history from : %s", conf.Storage.Dir)
+	}
+	g.History = history
+
+	// restore previous vote, if any
+	g.restored = g.restoreVote()
 
 	return g
 
@@ -147,271 +212,263 @@ func filterPossibilities(data []string) []string {
 	return res
 }
 
-// Link Twitch events to dedicated functions
-func (g *Gambling) twitchOnEventSetup() {
-	// Message handler, closure, because an access to *Gambling is needed
-	g.Twitch.OnPrivateMessage(func(message twitch.PrivateMessage) {
-
-		// the message does not contain the prefix, just return without doing nothing
-		if !strings.HasPrefix(message.Message, g.Config.Prefix) {
-			return
-		}
+// onConnect is triggered once the bot is connected
+func (g *Gambling) onConnect() {
+	g.say(g.text("hello", nil))
 
-		// Extract command and args from message
-		cmd, args := extractCommand(message.Message)
-		if cmd == "" {
-			return
-		}
+	// announce restored vote only once
+	if g.restored {
+		g.restored = false
+		g.announceRestoredVote()
 
-		switch cmd {
-		case "create":
-			g.handleCreate(message.User, args)
-			break
-		case "close":
-			g.handleClose(message.User)
-			break
-		case "roll":
-			g.handleRoll(message.User, args)
-			break
-		case "vote":
-			g.handleVote(message.User, args, g.Config.Verified)
-			break
-		case "delete":
-			g.handleDelete(message.User)
-			break
-		case "winners":
-			g.handleWinList(message.User)
-			break
-		case "reset":
-			g.handleReset(message.User)
-			break
-		case "stats":
-			g.handleStat(message.User, args)
-			break
-		default:
-			log.WithField("command", cmd).Warn("Unsupported command received")
-			g.say("Sorry but this is not a supported command")
+		// resume automatic closing
+		if g.CurrentVote.IsOpen && !g.CurrentVote.Deadline.IsZero() {
+			g.startTimer()
 		}
 
-	})
-
-	// On connect handler
-	g.Twitch.OnConnect(func() {
-		g.Twitch.Say(g.Config.Twitch.Channel, g.Config.Hello)
-	})
-
-}
-
-// Join channel
-func (g *Gambling) join() {
-	g.Twitch.Join(g.Config.Twitch.Channel)
+		// resume claim deadlines of winners
+		g.resumeClaims()
+	}
 }
 
-// Start is used to connect a gamble-bot instance to a twitch channel, and start the bot
-func (g *Gambling) Start() error {
-	log.Info("Bot instance is starting")
-	return g.Twitch.Connect()
+// command is a structure describing a chat command
+type command struct {
+	// Is admin permission needed ?
+	admin bool
+	// Command handler
+	run func(g *Gambling, user twitch.User, args []string)
 }
 
-// say will be used to send informations to twitch channel
-func (g *Gambling) say(message string) {
-	g.Twitch.Say(g.Config.Twitch.Channel, message)
+// commands supported by the bot, by name
+var commands = map[string]command{
+	"create":  {admin: true, run: (*Gambling).handleCreate},
+	"close":   {admin: true, run: (*Gambling).handleClose},
+	"extend":  {admin: true, run: (*Gambling).handleExtend},
+	"cancel":  {admin: true, run: (*Gambling).handleCancel},
+	"roll":    {admin: true, run: (*Gambling).handleRoll},
+	"reroll":  {admin: true, run: (*Gambling).handleReroll},
+	"resolve": {admin: true, run: (*Gambling).handleResolve},
+	"delete":  {admin: true, run: (*Gambling).handleDelete},
+	"winners": {admin: true, run: (*Gambling).handleWinList},
+	"reset":   {admin: true, run: (*Gambling).handleReset},
+	"stats":   {admin: true, run: (*Gambling).handleStat},
+	"history": {admin: true, run: (*Gambling).handleHistory},
+	"prize":   {admin: true, run: (*Gambling).handlePrize},
+	"vote":    {run: (*Gambling).handleVote},
+	"bet":     {run: (*Gambling).handleBet},
+	"balance": {run: (*Gambling).handleBalance},
+	"top":     {run: (*Gambling).handleTop},
+	"me":      {run: (*Gambling).handleMe},
+	"claim":   {run: (*Gambling).handleClaim},
 }
 
-// choices function is used to return all possibilites in a vote as a string
-func (g *Gambling) choices() string {
-	return strings.Join(g.CurrentVote.Possibilities, " or ")
-}
+// onPrivateMessage is used to dispatch a channel message to the matching command handler
+func (g *Gambling) onPrivateMessage(message twitch.PrivateMessage) {
 
-// whisper will be used to send whisper to some user with rate limit constraints
-func (g *Gambling) whisper(user string, message string) error {
+	// admins listed by ID can only be notified once their login is known
+	g.seeAdmin(message.User)
 
-	// setup a context with a timeout
-	ctx, cancel := context.WithTimeout(context.Background(), 1*time.Second)
-	// ensure cancel
-	defer cancel()
-	// wait with context
-	err := g.WhispRL.Wait(ctx)
+	// the message does not contain the prefix, just return without doing nothing
+	if !strings.HasPrefix(message.Message, g.Config.Prefix) {
+		return
+	}
 
-	// check for errors
-	if err != nil {
-		// setup a context with a timeout
-		// do not wait for a long time
-		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
-		// ensure cancel
-		defer cancel()
-		// wait with context
-		err := g.WarnRL.Wait(ctx)
-		if err != nil {
-			// log as warning
-			log.WithFields(log.Fields{
-				"message": message,
-				"user":    user,
-			}).Warn("Rate limit reached, private message not sent")
-			return errors.New("Can not send whisper nor warning message, rate limit reached")
-		}
+	// Extract command and args from message
+	cmd, args := extractCommand(message.Message)
+	if cmd == "" {
+		return
+	}
 
-		g.say("Warning, whisper rate limit reached, you may receive your vote acknowledgement later")
+	c, ok := commands[cmd]
+	if !ok {
+		log.WithField("command", cmd).Warn("Unsupported command received")
+		g.say(g.text("unsupported", nil))
+		return
+	}
 
-		return errors.New("Can not send whisper message, rate limit reached, warning message send")
+	// If user is not allowed, return without doing nothing
+	if !g.allowed(message.User, cmd, c.admin) {
+		return
 	}
 
-	// if rate limit not reach, send message
-	g.Twitch.Whisper(user, message)
+	c.run(g, message.User, args)
 
-	// logs message send as Info
-	log.WithFields(log.Fields{
-		"message": message,
-		"user":    user,
-	}).Info("Private message send")
+}
+
+// say will be used to send informations to twitch channel
+func (g *Gambling) say(message string) {
+	g.Scheduler.Say(g.Config.Twitch.Channel, message, LaneNormal)
+}
+
+// announce will be used to send results to twitch channel, before anything else waiting
+func (g *Gambling) announce(message string) {
+	g.Scheduler.Say(g.Config.Twitch.Channel, message, LaneAnnounce)
+}
+
+// whisper will be used to send a private message to a user, before anything else waiting
+func (g *Gambling) whisper(user string, message string) {
+	g.Scheduler.Whisper(user, message, LaneAnnounce)
+}
 
-	return err
+// choices function is used to return all possibilites in a vote as a string
+func (g *Gambling) choices() string {
+	return g.Messages.choices(g.CurrentVote)
 }
 
 // sayAt will be used to send messages to twitch channel with mentions to users passed as arguments
 func (g *Gambling) sayAt(message string, users []string) {
+	g.say(mention(message, users))
+}
+
+// announceAt will be used to send results to twitch channel with mentions to users passed as arguments
+func (g *Gambling) announceAt(message string, users []string) {
+	g.announce(mention(message, users))
+}
+
+// mention is used to prefix a message with mentions to users
+func mention(message string, users []string) string {
 
 	at := ""
 
 	for _, u := range users {
 		at = at + fmt.Sprintf("@%s ", u)
 	}
-	g.Twitch.Say(g.Config.Twitch.Channel, fmt.Sprintf("%s : %s", at, message))
-}
-
-// ackMessage is used to generated an ack message
-func ackMessage(valid bool, vote string) string {
-	// get current date
-	date := time.Now()
 
-	// tail of the message, used to specify sending date, since Twitch UI not clear about this
-	tail := fmt.Sprintf("(sent on %d-%02d-%02d)", date.Year(), date.Month(), date.Day())
+	return fmt.Sprintf("%s : %s", at, message)
+}
 
+// ackMessage is used to generated an ack message, ending with the sending date since Twitch UI not clear about this
+func (g *Gambling) ackMessage(valid bool, vote string) string {
 	// return a message for a valid vote
 	if valid {
-		return fmt.Sprintf("For your information, I correctly handled your vote for %s", vote) + " " + tail
+		return g.text("vote.ack", vars{"Vote": vote})
 	}
 
 	// return a kind error message if vote if note valid
-	return "Sorry but the vote command you send is not valid, you may have made a mistake, please retry" + " " + tail
+	return g.text("vote.invalid", nil)
 
 }
 
-// SendAcks is used to send Ack accumulted while rate limit is reached
-func (g *Gambling) SendAcks() {
-
-	// Mark Ack sending jobs as pending
-	g.CurrentVote.Acks.WIP = true
-
-	// loop until a drop is received
-	for {
-		select {
-		// if a Drop inscrution is received, leave
-		case <-g.CurrentVote.Acks.Drop:
-			log.Info("Message drop instruction received")
-			return
-		// if an ack is received handle it
-		case ack, ok := <-g.CurrentVote.Acks.Buffer:
-			// if channel is empty juste leave
-			if !ok {
-				// Mark ack sending jobs as done
-				log.Info("Acks WIP set to false")
-				g.CurrentVote.Acks.WIP = false
-				return
-			}
-
-			// Ensure a sleep of half a second between each messages, in order to be sure to not reach rate limit
-			time.Sleep((200 * time.Millisecond))
-
-			// try sending message from acks queue
-			err := g.whisper(ack.Username, ack.message)
-			if err != nil {
-				// If rate limit is reached drop and log
-				log.WithFields(log.Fields{
-					"message": ack.message,
-					"user":    ack.Username,
-				}).Warn("Rate limit reached, while sending late ACKs message dropped")
-				return
-			}
-		}
-	}
-}
-
 // Handlers for all the things !
 // create command handler
 func (g *Gambling) handleCreate(user twitch.User, args []string) {
 
-	// If user is not admin, return without doing nothing
-	if !checkPermission(user.Name, g.Config.Admins) {
-		return
-	}
-
 	// Check if vote exists, by default, IsOpen will be false
 	if g.CurrentVote.IsOpen {
-		g.say(fmt.Sprintf("There is already a vote going, you should delete it first with '%s delete'.", g.Config.Prefix))
+		g.say(g.text("create.open", nil))
 		return
 	}
 
-	if len(args) < 2 {
-		g.say("You need to pass the choices as arguments (2 at least)")
+	// An optional duration, an optional eligibility profile and an optional mode can be passed before choices
+	duration, args := extractDuration(args)
+	profile, eligibility, args := extractEligibility(args, g.Config)
+	mode, max, args := extractMode(args)
+
+	// numeric votes take an optional range instead of choices
+	var min, top *float64
+	if mode == modeNumber {
+		var ok bool
+		min, top, ok = extractRange(args)
+		if !ok {
+			g.say(g.text("create.range", nil))
+			return
+		}
+		args = nil
+	} else if len(args) < 2 {
+		g.say(g.text("create.choices", nil))
 		return
 	}
 
+	// Give back bets of a previous unresolved vote
+	g.refundBets()
+
+	g.CurrentVote.ID = newVoteID()
 	g.CurrentVote.IsOpen = true
+	g.CurrentVote.OpenedAt = now()
+	g.CurrentVote.ClosedAt = time.Time{}
 	g.CurrentVote.Votes = make(map[string]string)
+	g.CurrentVote.Ballots = make(map[string][]string)
+	g.CurrentVote.Voters = make(map[string]Voter)
+	g.CurrentVote.Mode = mode
+	g.CurrentVote.MaxChoices = max
+	g.CurrentVote.Profile = profile
+	g.CurrentVote.Eligibility = eligibility
+	g.CurrentVote.Min = min
+	g.CurrentVote.Max = top
 	g.CurrentVote.Possibilities = filterPossibilities(lower(args))
-
-	// Ensure a 500 items long ACK queue
-	g.CurrentVote.Acks = NewAcks()
-
-	g.say(fmt.Sprintf("There is a new vote! You can vote with '%s vote <vote> (choices are : %s)'", g.Config.Prefix, g.choices()))
+	g.CurrentVote.Bets = make(map[string]Bet)
+	g.CurrentVote.Winners = nil
+	g.CurrentVote.Draws = nil
+	g.CurrentVote.Claims = nil
+	g.CurrentVote.Prize = ""
+	g.CurrentVote.Seed = ""
+	g.CurrentVote.SeedHash = ""
+	g.CurrentVote.Outcome = ""
+	g.CurrentVote.Closest = nil
+	g.CurrentVote.Deadline = time.Time{}
+
+	if duration > 0 {
+		g.CurrentVote.Deadline = g.clockNow().Add(duration)
+		g.startTimer()
+	}
+
+	g.saveVote()
+	g.publish("create")
+
+	g.say(g.text("create", vars{
+		"Usage":    g.voteUsage(),
+		"Audience": eligibility.audience(g.Messages),
+		"Duration": duration,
+	}))
 
 	log.WithFields(log.Fields{
-		"choices":        g.CurrentVote.Possibilities,
-		"requested by":   user.DisplayName,
-		"acks queue len": bufferSize,
+		"choices":      g.CurrentVote.Possibilities,
+		"mode":         mode,
+		"profile":      profile,
+		"requested by": user.DisplayName,
+		"duration":     duration,
 	}).Info("Vote created")
 }
 
 // close vote handler
-func (g *Gambling) handleClose(user twitch.User) {
-	// If user is not admin, return without doing nothing
-	if !checkPermission(user.Name, g.Config.Admins) {
-		return
-	}
-
+func (g *Gambling) handleClose(user twitch.User, args []string) {
 	// Check if vote is open
 	if !g.CurrentVote.IsOpen {
-		g.say("Do not try to close an alreay closed vote !")
+		g.say(g.text("close.closed", nil))
 		return
 	}
 
-	g.CurrentVote.IsOpen = false
+	g.closeVote()
+}
 
-	// close channel
-	close(g.CurrentVote.Acks.Buffer)
+// closeVote closes the current vote and announces statistics
+func (g *Gambling) closeVote() {
 
-	// unpile, if verified
-	if g.Config.Verified {
-		go g.SendAcks()
-	}
+	// a closed vote does not need a timer anymore
+	g.stopTimer()
 
-	st := NewStatistics(g.CurrentVote)
+	g.CurrentVote.IsOpen = false
+	g.CurrentVote.ClosedAt = now()
+	// committed before anyone can roll
+	g.commitSeed()
 
-	var parts []string
+	g.saveVote()
+	g.publish("close")
 
-	for k, v := range st.Transformed {
-		parts = append(parts, fmt.Sprintf("%s : %d (%.2f%%)", k, len(v), (float64(len(v))/float64(st.Total))*100))
-	}
+	st := NewStatistics(g.CurrentVote)
 
 	log.Info("Vote closed")
 
-	g.say("Vote is now closed, time for statistics ! " + fmt.Sprintf("Participants : %d", st.Total) + " | " + strings.Join(parts, ", "))
+	g.say(g.text("close", vars{"Summary": closeSummary(g.Messages, g.CurrentVote, st)}))
+
+	if g.CurrentVote.fair() {
+		g.say(g.text("close.seed", vars{"Hash": g.CurrentVote.SeedHash}))
+	}
 
 }
 
 // handle a vote
-func (g *Gambling) handleVote(user twitch.User, args []string, verified bool) {
+func (g *Gambling) handleVote(user twitch.User, args []string) {
 
 	// Ensure the vote is open
 	if !g.CurrentVote.IsOpen {
@@ -419,102 +476,88 @@ func (g *Gambling) handleVote(user twitch.User, args []string, verified bool) {
 		return
 	}
 
+	// Ensure the user can vote
+	if !g.eligible(user) {
+		return
+	}
+
 	// Ensure there is args
 	if args == nil || len(args) < 1 {
-		if verified {
-			message := ackMessage(false, "")
-			err := g.whisper(user.Name, message)
-			// if an error occur, rate limit is reached try later and add it to ack queue
-			if err != nil {
-				log.WithFields(log.Fields{
-					"message": message,
-					"user":    user,
-					"valid":   false,
-				}).Info("Message added to ACKs queue")
-				g.CurrentVote.Acks.Buffer <- NewVoteAck(message, user.Name)
-			}
-		}
+		g.ack(user.Name, g.ackMessage(false, ""))
 		return
 	}
 
-	// Ensure vote is lowercase
-	vote := strings.ToLower(args[0])
-
-	// Check if vote is valid
-	if g.isVoteValid(vote) {
-		// If it is add it
-		g.CurrentVote.Votes[user.Name] = vote
-		// ensure bot is verified to send whispers
-		if verified {
-			message := ackMessage(true, vote)
-			err := g.whisper(user.Name, message)
-			// if an error occur, rate limit is reached try later and add it to ack queue
-			if err != nil {
-				log.WithFields(log.Fields{
-					"message": message,
-					"user":    user,
-					"valid":   true,
-				}).Info("Message added to ACKs queue")
-				g.CurrentVote.Acks.Buffer <- NewVoteAck(message, user.Name)
-			}
-		}
-	} else {
-		// ensure bot is verified to send whispers
-		if verified {
-			message := ackMessage(false, "")
-			err := g.whisper(user.Name, message)
-			// if an error occur, rate limit is reached try later and add it to ack queue
-			if err != nil {
-				log.WithFields(log.Fields{
-					"message": message,
-					"user":    user,
-					"valid":   false,
-				}).Info("Message added to ACKs queue")
-				g.CurrentVote.Acks.Buffer <- NewVoteAck(message, user.Name)
-			}
-		}
+	// Check if vote is valid for the vote mode, choices are lowercased
+	choices, ok := g.parseBallot(args)
+	if !ok {
+		g.ack(user.Name, g.ackMessage(false, ""))
+		return
+	}
+
+	// If it is add it
+	g.castVote(user, choices)
+
+	// a bet follows the (first) vote of its owner, unless its choice is still picked
+	if bet, ok := g.CurrentVote.Bets[userID(user)]; ok && !inBallot(choices, bet.Choice) {
+		bet.Choice = choices[0]
+		g.CurrentVote.Bets[userID(user)] = bet
 	}
 
+	g.saveVote()
+	g.publish("vote")
+
+	g.ack(user.Name, g.ackMessage(true, g.describeBallot(choices)))
+
 }
 
 // handle a vote delete
-func (g *Gambling) handleDelete(user twitch.User) {
+func (g *Gambling) handleDelete(user twitch.User, args []string) {
 
-	if !checkPermission(user.Name, g.Config.Admins) {
-		return
-	}
+	// acks of a deleted vote are meaningless
+	g.Acks.Clear()
 
-	// if there is a working job sending acks
-	if g.CurrentVote.Acks.WIP {
-		// Drop all jobs
-		g.CurrentVote.Acks.Drop <- true
-	}
+	// Stop automatic closing
+	g.stopTimer()
+
+	// Give bets back
+	g.refundBets()
 
 	// Create a new empty vote
 	g.CurrentVote = new(Vote)
 
+	g.clearVote()
+	g.publish("delete")
+
 	log.Info("Vote deleted")
 
-	g.say("Vote deleted !")
+	g.say(g.text("delete", nil))
 
 }
 
 // handle a vote reset
-func (g *Gambling) handleReset(user twitch.User) {
+func (g *Gambling) handleReset(user twitch.User, args []string) {
 
-	if !checkPermission(user.Name, g.Config.Admins) {
+	// a closed vote is archived, its voters are kept for history and leaderboard
+	if g.CurrentVote.exists() && !g.CurrentVote.IsOpen {
+		g.say(g.text("reset.closed", nil))
 		return
 	}
 
-	// if there is a working job sending acks
-	if g.CurrentVote.Acks.WIP {
-		// Drop all jobs
-		g.CurrentVote.Acks.Drop <- true
-	}
-
-	g.CurrentVote.Acks.Buffer = make(chan VoteAck, bufferSize)
+	// acks of dropped votes are meaningless
+	g.Acks.Clear()
 
 	g.CurrentVote.Votes = make(map[string]string)
+	g.CurrentVote.Ballots = make(map[string][]string)
+
+	// Give bets back
+	g.refundBets()
+
+	// Stop automatic closing
+	g.stopTimer()
+	g.CurrentVote.Deadline = time.Time{}
+
+	g.saveVote()
+	g.publish("reset")
 
 	log.Info("Vote reset")
 }
@@ -522,141 +565,208 @@ func (g *Gambling) handleReset(user twitch.User) {
 // handle a call to stats generation (public or private)
 func (g *Gambling) handleStat(user twitch.User, args []string) {
 
-	if !checkPermission(user.Name, g.Config.Admins) {
+	// text by default
+	format := "text"
+	if len(args) > 0 {
+		format = strings.ToLower(args[0])
+	}
+
+	// create stats using requested format
+	stats, err := createFormattedStat(g.CurrentVote, format)
+	if err != nil {
+		log.WithError(err).Warn("Unsupported statistics format")
+		g.say(g.text("stats.format", vars{"Format": format}))
 		return
 	}
 
-	// create stats and store it into a string
-	stats := createStat(g.CurrentVote)
-	err := statsToFile(stats, g.Config.Stats.Dir)
+	// one file per vote
+	err = statsToFile(stats, g.Config.Stats.Dir, statsFileName(g.CurrentVote, format))
 	if err != nil {
 		log.Error(err.Error())
-		g.say("Error generating statistics")
+		g.say(g.text("stats.error", nil))
+		return
 	}
-	g.say("Statistics generated in private mode")
+	g.say(g.text("stats.private", nil))
 
 }
 
 // handle a call to winners list
-func (g *Gambling) handleWinList(user twitch.User) {
-
-	if !checkPermission(user.Name, g.Config.Admins) {
-		return
-	}
+func (g *Gambling) handleWinList(user twitch.User, args []string) {
 
 	if g.CurrentVote.IsOpen {
-		g.sayAt(fmt.Sprintf("Hey ! The vote isn't closed ! Close it using command : '%s close'", g.Config.Prefix), g.Config.Admins)
+		g.sayAt(g.text("not_closed", nil), g.admins())
 		return
 	}
 
 	if len(g.CurrentVote.Winners) <= 0 {
-		g.sayAt("There is no selected winners for this vote", g.Config.Admins)
+		g.sayAt(g.text("winners.none", nil), g.admins())
 		return
 	}
 
-	g.sayAt(fmt.Sprintf("Ordered list of winners for this vote : %s", strings.Join(g.CurrentVote.Winners, " - ")), g.Config.Admins)
+	g.sayAt(g.text("winners", vars{"Winners": g.CurrentVote.winnerList(g.Messages)}), g.admins())
 
 }
 
-// Roll a winner, ensure no duplicates and append to winners list
-func (g *Gambling) rollWinner(team string) (string, error) {
-	// slice of user, filter out already selected winners
-	var candidates []string
+// allTeams is the pseudo team used to roll winners among every voter
+const allTeams = "all"
 
-	// for all votes and associated user
-	for user, vote := range g.CurrentVote.Votes {
+// maxRoll is the number of winners which can be rolled at once
+const maxRoll = 10
 
-		// check for duplicate
-		duplicate := false
-		// if there is already winners in the list
-		if len(g.CurrentVote.Winners) > 0 {
-			// for all winners
-			for _, win := range g.CurrentVote.Winners {
-				// is this user a duplicate ?
-				if user == win {
-					// if so mark it as duplicate
-					duplicate = true
-				}
-			}
-		}
+// newDraw returns how winners of a team are rolled
+func (g *Gambling) newDraw(team string) Draw {
+	return Draw{
+		Team:    team,
+		Closest: g.CurrentVote.mode() == modeNumber && g.CurrentVote.Outcome != "" && team == g.CurrentVote.Outcome,
+		Tickets: g.Config.Weights.Tickets,
+	}
+}
 
-		// add user if it's not a duplicate
-		// if there is no already selected winners, duplicate will always be false so add everyone
-		// check vote of for this user, ensure it matches the argument
-		if !duplicate && vote == team {
-			candidates = append(candidates, user)
+// Roll winners, ensure no duplicates and append them to winners list
+// the draw team can be allTeams to roll among every voter
+// returns the user IDs of at most n winners, fewer if there is not enough candidates
+func (g *Gambling) rollWinners(d Draw, n int) ([]string, error) {
+	// already selected winners are filtered out
+	if len(g.CurrentVote.candidates(d, g.CurrentVote.Winners)) <= 0 {
+		if d.Team == allTeams {
+			return nil, errors.New(g.text("roll.empty_all", nil))
 		}
+		return nil, errors.New(g.text("roll.empty", vars{"Team": d.Team}))
 	}
 
-	if len(candidates) <= 0 {
-		return "", fmt.Errorf("Sorry not enough candidates to roll a winner in team %s", team)
-	}
+	var selected []string
+	for len(selected) < n {
+		candidates := g.CurrentVote.candidates(d, g.CurrentVote.Winners)
+		if len(candidates) == 0 {
+			break
+		}
 
-	selected := candidates[rand.Intn(len(candidates))]
+		winner := g.CurrentVote.draw(g.CurrentVote.generator(), d, candidates)
 
-	g.CurrentVote.Winners = append(g.CurrentVote.Winners, selected)
+		g.CurrentVote.Winners = append(g.CurrentVote.Winners, winner)
+		g.CurrentVote.Draws = append(g.CurrentVote.Draws, d)
+		g.openClaim(winner)
+		selected = append(selected, winner)
 
-	log.WithField("user", selected).Warn("Randomly selected user, added to winners list")
+		log.WithField("user", winner).Warn("Randomly selected user, added to winners list")
+	}
+
+	g.saveVote()
+	g.publish("roll")
 
 	return selected, nil
 }
 
-// handle roll and select winner
+// handle roll and select winners
 func (g *Gambling) handleRoll(user twitch.User, args []string) {
 
-	if !checkPermission(user.Name, g.Config.Admins) {
-		return
-	}
-
 	if len(g.CurrentVote.Votes) == 0 {
-		g.sayAt("You can not roll since there is no vote", g.Config.Admins)
+		g.sayAt(g.text("roll.none", nil), g.admins())
 		return
 	}
 
 	if g.CurrentVote.IsOpen {
-		g.sayAt(fmt.Sprintf("Hey ! The vote isn't closed ! Close it using command : '%s close'", g.Config.Prefix), g.Config.Admins)
+		g.sayAt(g.text("not_closed", nil), g.admins())
 		return
 	}
 
 	if len(args) < 1 {
-		g.sayAt(fmt.Sprintf("You must specify the winner (choices are : %s)", g.choices()), g.Config.Admins)
+		g.sayAt(g.text("roll.usage", vars{"All": allTeams}), g.admins())
 		return
 	}
 
-	if !g.isVoteValid(args[0]) {
-		g.sayAt(fmt.Sprintf("%s is not a correct roll option (choices are : %s)", args[0], g.choices()), g.Config.Admins)
+	team := g.CurrentVote.normalize(args[0])
+
+	// a choice named all is still a team
+	if strings.EqualFold(args[0], allTeams) && !g.isVoteValid(team) {
+		team = allTeams
+	}
+
+	// the result of a numeric vote may be out of its range
+	if team != allTeams && !g.isVoteValid(team) && team != g.CurrentVote.Outcome {
+		g.sayAt(g.text("roll.invalid", vars{"Team": args[0], "All": allTeams}), g.admins())
 		return
 	}
 
-	winner, err := g.rollWinner(args[0])
+	// once resolved, winners can only be rolled among correct voters
+	if g.CurrentVote.Outcome != "" && team == allTeams {
+		g.sayAt(g.text("roll.resolved", vars{"Outcome": g.CurrentVote.Outcome}), g.admins())
+		return
+	}
+	if g.CurrentVote.Outcome != "" && team != g.CurrentVote.Outcome {
+		g.sayAt(g.text("roll.lost", vars{"Team": team, "Outcome": g.CurrentVote.Outcome}), g.admins())
+		return
+	}
+
+	n := 1
+	if len(args) > 1 {
+		c, err := strconv.Atoi(args[1])
+		if err != nil || c <= 0 || c > maxRoll {
+			g.sayAt(g.text("roll.count", vars{"Max": maxRoll, "Team": args[0]}), g.admins())
+			return
+		}
+		n = c
+	}
+
+	winners, err := g.rollWinners(g.newDraw(team), n)
 	if err != nil {
-		g.sayAt(err.Error(), g.Config.Admins)
+		g.sayAt(err.Error(), g.admins())
 		return
 	}
 
-	g.sayAt(fmt.Sprintf("And... The winner is... %s", winner), g.Config.Admins)
+	names := strings.Join(g.CurrentVote.names(winners), ", ")
 
-	// Send private message to the winner if verified
-	if g.Config.Verified {
-		// get current date
-		date := time.Now()
+	if len(winners) == 1 && n == 1 {
+		g.announceAt(g.text("roll.winner", vars{"Winner": names, "Claim": g.claimUsage()}), g.admins())
+	} else {
+		g.announceAt(g.text("roll.winners", vars{"Winners": names, "Short": len(winners) < n, "Claim": g.claimUsage()}), g.admins())
+	}
 
-		// tail of the message, used to specify sending date, since Twitch UI not clear about this
-		tail := fmt.Sprintf("(sent on %d-%02d-%02d)", date.Year(), date.Month(), date.Day())
+	g.revealSeed()
+	g.sendPrizes(winners)
+	g.notifyWinners(winners)
 
-		for _, adm := range g.Config.Admins {
-			g.Twitch.Whisper(adm, fmt.Sprintf("Psstt, selected winner is : %s %s", winner, tail))
-		}
+}
 
-		// Send the message
-		g.Twitch.Whisper(winner, fmt.Sprintf("Congrat's ! You're the winner ! Contact the streamer to get your reward ! %s", tail))
+// notifyWinners is used to send a private message to admins and to each winner, if verified
+func (g *Gambling) notifyWinners(winners []string) {
+	if !g.Config.Verified {
+		return
+	}
+
+	names := strings.Join(g.CurrentVote.names(winners), ", ")
+
+	for _, adm := range g.admins() {
+		if len(winners) == 1 {
+			g.whisper(adm, g.text("roll.admin", vars{"Winner": names}))
+		} else {
+			g.whisper(adm, g.text("roll.admin_many", vars{"Winners": names}))
+		}
 	}
 
+	// Send the messages
+	for _, winner := range winners {
+		// winners who got a prize code already know they won
+		if g.CurrentVote.Claims[winner].Prize != "" {
+			continue
+		}
+		if g.Config.Rolls.ClaimTimeout > 0 {
+			g.whisper(g.CurrentVote.login(winner), g.text("roll.congrats_claim", vars{"Timeout": g.Config.Rolls.ClaimTimeout}))
+			continue
+		}
+		g.whisper(g.CurrentVote.login(winner), g.text("roll.congrats", nil))
+	}
 }
 
 // Is a vote valid ?
 func (g *Gambling) isVoteValid(vote string) bool {
 
+	// numeric votes accept numbers within the range
+	if g.CurrentVote.mode() == modeNumber {
+		f, ok := parseNumber(vote)
+		return ok && g.CurrentVote.inRange(f)
+	}
+
 	// Loop over possibilities
 	for _, p := range g.CurrentVote.Possibilities {
 		// ensure lowercase on everything
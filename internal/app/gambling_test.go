@@ -1,8 +1,10 @@
 package app
 
 import (
-	"sync"
+	"fmt"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 )
@@ -34,20 +36,217 @@ func testFilterPossibilities(t *testing.T) {
 	assert.Equal(t, 2, len(res))
 }
 
-func TestVote(t *testing.T) {
-	g := &Gambling{
-		CurrentVote: &Vote{
-			Possibilities: make([]string, 0),
-			Votes:         make(map[string]string),
-			Acks:          NewAcks(),
-		},
+// testConf returns a configuration used by tests
+func testConf() Conf {
+	return Conf{
+		Twitch:   TwitchCreds{Channel: "chan"},
+		Admins:   []string{"admin"},
+		Hello:    "Hello",
+		Prefix:   "!gamble",
+		Verified: true,
+		Wallet:   Wallet{Initial: 1000, Currency: "points"},
 	}
-	wait := sync.WaitGroup{}
-	wait.Add(1)
-	go func() {
-		g.SendAcks()
-		wait.Done()
-	}()
-	g.CurrentVote.Acks.Drop <- true
-	wait.Wait()
+}
+
+// newTestBot is used to create a Bot instance plugged on a fake transport
+func newTestBot(t *testing.T, conf Conf) (*Bot, *fakeTransport) {
+	date := time.Date(2020, 4, 2, 12, 0, 0, 0, time.UTC)
+	now = func() time.Time { return date }
+	t.Cleanup(func() { now = time.Now })
+
+	fake := new(fakeTransport)
+	b := newBot(conf, fake, newFakeClock(date))
+
+	return b, fake
+}
+
+// newTestGambling is used to create a Gambling instance for channel "chan" plugged on a fake transport
+func newTestGambling(t *testing.T, conf Conf) (*Gambling, *fakeTransport) {
+	b, fake := newTestBot(t, conf)
+
+	return b.Sessions["chan"], fake
+}
+
+func TestConnect(t *testing.T) {
+	b, fake := newTestBot(t, testConf())
+
+	assert.Nil(t, b.Start())
+
+	assert.Equal(t, []string{"chan"}, fake.joined)
+	assert.Equal(t, []string{"Hello"}, fake.Said())
+}
+
+func TestVoteLifecycle(t *testing.T) {
+	g, fake := newTestGambling(t, testConf())
+
+	// only admins can create a vote
+	fake.send("chan", "alice", "!gamble create val pl")
+	assert.Nil(t, fake.Said())
+
+	fake.send("chan", "admin", "!gamble create Val pl val")
+	assert.Equal(t, []string{"There is a new vote! You can vote with '!gamble vote <vote> (choices are : val or pl)'"}, fake.Said())
+	assert.Equal(t, []string{"val", "pl"}, g.CurrentVote.Possibilities)
+
+	fake.send("chan", "admin", "!gamble create a b")
+	assert.Equal(t, []string{"There is already a vote going, you should delete it first with '!gamble delete'."}, fake.Said())
+
+	fake.send("chan", "alice", "!gamble vote VAL")
+	fake.send("chan", "bob", "!gamble vote pl")
+	fake.send("chan", "carol", "!gamble vote nope")
+	fake.send("chan", "dave", "!gamble vote")
+	fake.send("chan", "bob", "!gamble vote val")
+
+	valid := "For your information, I correctly handled your vote for %s (sent on 2020-04-02)"
+	invalid := "Sorry but the vote command you send is not valid, you may have made a mistake, please retry (sent on 2020-04-02)"
+	assert.Equal(t, []sentMessage{
+		{To: "alice", Message: fmt.Sprintf(valid, "val")},
+		{To: "bob", Message: fmt.Sprintf(valid, "pl")},
+		{To: "carol", Message: invalid},
+		{To: "dave", Message: invalid},
+		{To: "bob", Message: fmt.Sprintf(valid, "val")},
+	}, fake.Whispered())
+	assert.Nil(t, fake.Said())
+
+	// rolling is not possible while the vote is open
+	fake.send("chan", "admin", "!gamble roll val")
+	assert.Equal(t, []string{"@admin  : Hey ! The vote isn't closed ! Close it using command : '!gamble close'"}, fake.Said())
+
+	fake.send("chan", "admin", "!gamble close")
+	assert.Equal(t, []string{"Vote is now closed, time for statistics ! Participants : 2 | val : 2 (100.00%)"}, fake.Said())
+
+	fake.send("chan", "admin", "!gamble close")
+	assert.Equal(t, []string{"Do not try to close an alreay closed vote !"}, fake.Said())
+
+	// votes are ignored once closed
+	fake.send("chan", "carol", "!gamble vote val")
+	assert.Nil(t, fake.Whispered())
+
+	fake.send("chan", "admin", "!gamble roll")
+	assert.Equal(t, []string{"@admin  : You must specify the winner (choices are : val or pl or all)"}, fake.Said())
+
+	fake.send("chan", "admin", "!gamble roll nope")
+	assert.Equal(t, []string{"@admin  : nope is not a correct roll option (choices are : val or pl or all)"}, fake.Said())
+
+	fake.send("chan", "admin", "!gamble roll pl")
+	assert.Equal(t, []string{"@admin  : Sorry not enough candidates to roll a winner in team pl"}, fake.Said())
+
+	fake.send("chan", "admin", "!gamble roll val")
+	fake.send("chan", "admin", "!gamble roll val")
+	said := fake.Said()
+	assert.Len(t, said, 2)
+	first := strings.TrimPrefix(said[0], "@admin  : And... The winner is... ")
+	second := strings.TrimPrefix(said[1], "@admin  : And... The winner is... ")
+	assert.ElementsMatch(t, []string{"alice", "bob"}, []string{first, second})
+	assert.Equal(t, []sentMessage{
+		{To: "admin", Message: fmt.Sprintf("Psstt, selected winner is : %s (sent on 2020-04-02)", first)},
+		{To: first, Message: "Congrat's ! You're the winner ! Contact the streamer to get your reward ! (sent on 2020-04-02)"},
+		{To: "admin", Message: fmt.Sprintf("Psstt, selected winner is : %s (sent on 2020-04-02)", second)},
+		{To: second, Message: "Congrat's ! You're the winner ! Contact the streamer to get your reward ! (sent on 2020-04-02)"},
+	}, fake.Whispered())
+
+	fake.send("chan", "admin", "!gamble roll val")
+	assert.Equal(t, []string{"@admin  : Sorry not enough candidates to roll a winner in team val"}, fake.Said())
+
+	fake.send("chan", "admin", "!gamble winners")
+	assert.Equal(t, []string{fmt.Sprintf("@admin  : Ordered list of winners for this vote : %s - %s", first, second)}, fake.Said())
+
+	fake.send("chan", "admin", "!gamble delete")
+	assert.Equal(t, []string{"Vote deleted !"}, fake.Said())
+	assert.False(t, g.CurrentVote.IsOpen)
+	assert.Nil(t, g.CurrentVote.Votes)
+}
+
+func TestVoteReset(t *testing.T) {
+	g, fake := newTestGambling(t, testConf())
+
+	fake.send("chan", "admin", "!gamble create val pl")
+	fake.send("chan", "alice", "!gamble vote val")
+	fake.Said()
+	fake.Whispered()
+
+	fake.send("chan", "alice", "!gamble reset")
+	assert.Len(t, g.CurrentVote.Votes, 1)
+
+	fake.send("chan", "admin", "!gamble reset")
+	assert.Len(t, g.CurrentVote.Votes, 0)
+	assert.True(t, g.CurrentVote.IsOpen)
+
+	fake.send("chan", "bob", "!gamble vote pl")
+	fake.send("chan", "admin", "!gamble close")
+	assert.Equal(t, []string{"Vote is now closed, time for statistics ! Participants : 1 | pl : 1 (100.00%)"}, fake.Said())
+}
+
+func TestRollMany(t *testing.T) {
+	g, fake := newTestGambling(t, testConf())
+
+	fake.send("chan", "admin", "!gamble create val pl")
+	fake.send("chan", "alice", "!gamble vote val")
+	fake.send("chan", "bob", "!gamble vote val")
+	fake.send("chan", "carol", "!gamble vote val")
+	fake.send("chan", "dave", "!gamble vote pl")
+	fake.send("chan", "admin", "!gamble close")
+	fake.Said()
+	fake.Whispered()
+
+	for _, count := range []string{"0", "11", "two"} {
+		fake.send("chan", "admin", "!gamble roll val "+count)
+		assert.Equal(t, []string{"@admin  : You must specify a number of winners between 1 and 10 (example : '!gamble roll val 3')"}, fake.Said())
+	}
+
+	fake.send("chan", "admin", "!gamble roll val 2")
+	said := fake.Said()
+	assert.Len(t, said, 1)
+	names := strings.TrimPrefix(said[0], "@admin  : And... The winners are... ")
+	rolled := strings.Split(names, ", ")
+	assert.Len(t, rolled, 2)
+	assert.Subset(t, []string{"alice", "bob", "carol"}, rolled)
+	assert.Len(t, g.CurrentVote.Winners, 2)
+
+	// one whisper for admins, then one for each winner
+	assert.Equal(t, []sentMessage{
+		{To: "admin", Message: fmt.Sprintf("Psstt, selected winners are : %s (sent on 2020-04-02)", names)},
+		{To: rolled[0], Message: "Congrat's ! You're the winner ! Contact the streamer to get your reward ! (sent on 2020-04-02)"},
+		{To: rolled[1], Message: "Congrat's ! You're the winner ! Contact the streamer to get your reward ! (sent on 2020-04-02)"},
+	}, fake.Whispered())
+
+	// already rolled winners are not drawn again, across teams
+	fake.send("chan", "admin", "!gamble roll ALL 5")
+	said = fake.Said()
+	assert.Len(t, said, 1)
+	assert.True(t, strings.HasSuffix(said[0], " (no more candidates)"))
+	assert.ElementsMatch(t, []string{"id-alice", "id-bob", "id-carol", "id-dave"}, g.CurrentVote.Winners)
+	fake.Whispered()
+
+	fake.send("chan", "admin", "!gamble roll all")
+	assert.Equal(t, []string{"@admin  : Sorry not enough candidates to roll a winner among voters"}, fake.Said())
+
+	// once resolved, everyone can not be rolled anymore
+	fake.send("chan", "admin", "!gamble resolve pl")
+	fake.Said()
+	fake.send("chan", "admin", "!gamble roll all")
+	assert.Equal(t, []string{"@admin  : The vote is resolved, you can only roll a winner among pl voters"}, fake.Said())
+}
+
+func TestUnverifiedVote(t *testing.T) {
+	conf := testConf()
+	conf.Verified = false
+	g, fake := newTestGambling(t, conf)
+
+	fake.send("chan", "admin", "!gamble create val pl")
+	fake.send("chan", "alice", "!gamble vote val")
+	fake.send("chan", "bob", "!gamble vote nope")
+
+	assert.Nil(t, fake.Whispered())
+	assert.Equal(t, map[string]string{"id-alice": "val"}, g.CurrentVote.Votes)
+}
+
+func TestUnsupportedCommand(t *testing.T) {
+	_, fake := newTestGambling(t, testConf())
+
+	fake.send("chan", "alice", "hello !gamble create")
+	fake.send("chan", "alice", "!gamble")
+	assert.Nil(t, fake.Said())
+
+	fake.send("chan", "alice", "!gamble dance")
+	assert.Equal(t, []string{"Sorry but this is not a supported command"}, fake.Said())
 }
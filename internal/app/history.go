@@ -0,0 +1,258 @@
+package app
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/apex/log"
+	twitch "github.com/gempir/go-twitch-irc/v2"
+)
+
+// historyFile is the name of the file used to store past votes
+const historyFile = "history.json"
+
+// Number of votes summarised by the history command, by default and at most
+const (
+	defaultHistory = 3
+	maxHistory     = 10
+)
+
+// History is a structure containing all completed votes of a channel, oldest first
+type History struct {
+	mutex sync.Mutex
+	// File used to persist votes, memory only if empty
+	path string
+	// Archived votes
+	Votes []*Vote
+}
+
+// NewHistory is used to init a History, loading votes stored inside dir
+// if dir is empty, votes are kept in memory only
+func NewHistory(dir string) (*History, error) {
+	h := &History{Votes: []*Vote{}}
+
+	if dir == "" {
+		return h, nil
+	}
+
+	err := os.MkdirAll(dir, 0755)
+	if err != nil {
+		return nil, err
+	}
+
+	h.path = filepath.Join(dir, historyFile)
+
+	data, err := ioutil.ReadFile(h.path)
+	if os.IsNotExist(err) {
+		return h, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	err = json.Unmarshal(data, &h.Votes)
+	if err != nil {
+		return nil, fmt.Errorf("Error reading history file %s : %s", h.path, err)
+	}
+
+	return h, nil
+}
+
+// Archive stores a copy of a vote, replacing the archived one with the same identifier
+func (h *History) Archive(vote *Vote) error {
+	// copied, the current vote is still updated by later commands (roll, resolve)
+	data, err := json.Marshal(vote)
+	if err != nil {
+		return err
+	}
+	archived := new(Vote)
+	err = json.Unmarshal(data, archived)
+	if err != nil {
+		return err
+	}
+
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	replaced := false
+	for i, v := range h.Votes {
+		if v.ID == vote.ID {
+			// another vote with the same identifier, never lose the archived one
+			if !v.OpenedAt.Equal(vote.OpenedAt) {
+				return fmt.Errorf("Vote %s opened on %s is already archived", vote.ID, v.OpenedAt)
+			}
+			h.Votes[i] = archived
+			replaced = true
+			break
+		}
+	}
+	if !replaced {
+		h.Votes = append(h.Votes, archived)
+	}
+
+	return h.save()
+}
+
+// Recent returns the n last archived votes, newest first
+func (h *History) Recent(n int) []*Vote {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	var res []*Vote
+	for i := len(h.Votes) - 1; i >= 0 && len(res) < n; i-- {
+		res = append(res, h.Votes[i])
+	}
+
+	return res
+}
+
+// Find returns the archived vote with the given identifier, nil if there is none
+func (h *History) Find(id string) *Vote {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	for _, v := range h.Votes {
+		if v.ID == id {
+			return v
+		}
+	}
+
+	return nil
+}
+
+// save writes votes into the history file, if any
+func (h *History) save() error {
+	if h.path == "" {
+		return nil
+	}
+
+	data, err := json.Marshal(h.Votes)
+	if err != nil {
+		return err
+	}
+
+	return writeFileAtomic(h.path, data)
+}
+
+// summary returns a one line summary of a vote : identifier, date, choices, participants, result and winners
+func (v *Vote) summary(c *Catalogue) string {
+	return c.render("history.vote", vars{
+		"ID":      v.ID,
+		"Closed":  v.ClosedAt.Format("2006-01-02"),
+		"Choices": c.choices(v),
+		"Total":   len(v.Votes),
+		"Outcome": v.Outcome,
+		"Winners": v.winnerList(c),
+	})
+}
+
+// archiveVote is used to archive the current vote, once closed
+func (g *Gambling) archiveVote() {
+	if g.CurrentVote.IsOpen || g.CurrentVote.ClosedAt.IsZero() {
+		return
+	}
+
+	err := g.History.Archive(g.CurrentVote)
+	if err != nil {
+		log.WithError(err).Error("Error archiving vote")
+	}
+}
+
+// handle a call to the history of past votes
+func (g *Gambling) handleHistory(user twitch.User, args []string) {
+
+	n := defaultHistory
+	if len(args) > 0 {
+		c, err := strconv.Atoi(args[0])
+		if err != nil || c <= 0 {
+			g.sayAt(g.text("history.usage", nil), g.admins())
+			return
+		}
+		n = c
+	}
+	if n > maxHistory {
+		n = maxHistory
+	}
+
+	votes := g.History.Recent(n)
+	if len(votes) == 0 {
+		g.sayAt(g.text("history.none", nil), g.admins())
+		return
+	}
+
+	var summaries []string
+	for _, v := range votes {
+		summaries = append(summaries, v.summary(g.Messages))
+	}
+
+	g.sayAt(g.text("history", vars{"Count": len(votes), "Votes": summaries}), g.admins())
+}
+
+// openHistory is used to load the history of a channel from a config file,
+// the channel can be omitted if the config serves a single channel
+func openHistory(confPath string, channel string) (*History, error) {
+	var conf Conf
+	conf.getConf(confPath)
+
+	confs := conf.channelConfs()
+
+	var c *Conf
+	for i := range confs {
+		if channel == "" && len(confs) == 1 || strings.EqualFold(confs[i].Twitch.Channel, channel) {
+			c = &confs[i]
+			break
+		}
+	}
+	if c == nil {
+		return nil, fmt.Errorf("Unknown channel %q, a channel must be given when several are configured", channel)
+	}
+
+	if c.Storage.Dir == "" {
+		return nil, fmt.Errorf("No storage directory configured, votes are not archived")
+	}
+
+	return NewHistory(c.Storage.Dir)
+}
+
+// ListHistory writes a summary of the n last votes of a channel, newest first
+func ListHistory(w io.Writer, confPath string, channel string, n int) error {
+	h, err := openHistory(confPath, channel)
+	if err != nil {
+		return err
+	}
+
+	for _, v := range h.Recent(n) {
+		fmt.Fprintln(w, v.summary(defaultCatalogue))
+	}
+
+	return nil
+}
+
+// ShowHistory writes statistics of a past vote of a channel, using a format (text, json or csv)
+func ShowHistory(w io.Writer, confPath string, channel string, id string, format string) error {
+	h, err := openHistory(confPath, channel)
+	if err != nil {
+		return err
+	}
+
+	v := h.Find(id)
+	if v == nil {
+		return fmt.Errorf("Unknown vote %s", id)
+	}
+
+	stats, err := createFormattedStat(v, format)
+	if err != nil {
+		return err
+	}
+
+	_, err = w.Write(stats)
+
+	return err
+}
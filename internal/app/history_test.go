@@ -0,0 +1,122 @@
+package app
+
+import (
+	"bytes"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHistory(t *testing.T) {
+	dir := t.TempDir()
+
+	h, err := NewHistory(dir)
+	assert.Nil(t, err)
+	assert.Empty(t, h.Recent(3))
+
+	v := &Vote{ID: "a", Possibilities: []string{"val", "pl"}, Votes: map[string]string{"alice": "val"}}
+	assert.Nil(t, h.Archive(v))
+	assert.Nil(t, h.Archive(&Vote{ID: "b"}))
+
+	// archived votes are copies, archiving again replaces them
+	v.Outcome = "val"
+	assert.Equal(t, "", h.Find("a").Outcome)
+	assert.Nil(t, h.Archive(v))
+	assert.Equal(t, "val", h.Find("a").Outcome)
+	assert.Nil(t, h.Find("c"))
+
+	// another vote with the same identifier does not replace the archived one
+	assert.NotNil(t, h.Archive(&Vote{ID: "a", OpenedAt: time.Date(2020, 4, 2, 12, 0, 0, 0, time.UTC)}))
+	assert.Equal(t, "val", h.Find("a").Outcome)
+
+	recent := h.Recent(3)
+	assert.Len(t, recent, 2)
+	assert.Equal(t, "b", recent[0].ID)
+	assert.Equal(t, "a", recent[1].ID)
+
+	// votes are persisted
+	h, err = NewHistory(dir)
+	assert.Nil(t, err)
+	assert.Len(t, h.Votes, 2)
+	assert.Equal(t, map[string]string{"alice": "val"}, h.Find("a").Votes)
+}
+
+func TestHistoryCommand(t *testing.T) {
+	g, fake := newTestGambling(t, testConf())
+
+	fake.send("chan", "admin", "!gamble history")
+	assert.Equal(t, []string{"@admin  : There is no past vote"}, fake.Said())
+
+	fake.send("chan", "admin", "!gamble create val pl")
+	fake.send("chan", "alice", "!gamble vote val")
+	fake.send("chan", "admin", "!gamble close")
+	first := g.CurrentVote.ID
+	assert.Len(t, first, 16)
+	fake.send("chan", "admin", "!gamble resolve val")
+	fake.send("chan", "admin", "!gamble roll val")
+	fake.send("chan", "admin", "!gamble delete")
+
+	// open votes are not archived
+	fake.send("chan", "admin", "!gamble create number 0 10")
+	fake.send("chan", "admin", "!gamble history")
+	assert.Len(t, g.History.Votes, 1)
+
+	fake.send("chan", "bob", "!gamble vote 4")
+	fake.send("chan", "admin", "!gamble close")
+	second := g.CurrentVote.ID
+	fake.Said()
+
+	fake.send("chan", "admin", "!gamble history")
+	assert.Equal(t, []string{"@admin  : Last 2 votes : " +
+		second + " (2020-04-02) a number between 0 and 10, 1 votes | " +
+		first + " (2020-04-02) val or pl, 1 votes, result val, winners alice"}, fake.Said())
+
+	fake.send("chan", "admin", "!gamble history 1")
+	assert.Equal(t, []string{"@admin  : Last 1 votes : " + second + " (2020-04-02) a number between 0 and 10, 1 votes"}, fake.Said())
+
+	fake.send("chan", "admin", "!gamble history nope")
+	assert.Equal(t, []string{"@admin  : You must specify a number of votes (example : '!gamble history 5')"}, fake.Said())
+}
+
+func TestResetClosedVote(t *testing.T) {
+	g, fake := newTestGambling(t, testConf())
+
+	fake.send("chan", "admin", "!gamble create val pl")
+	fake.send("chan", "alice", "!gamble vote val")
+	fake.send("chan", "bob", "!gamble vote pl")
+	fake.send("chan", "admin", "!gamble close")
+	fake.send("chan", "admin", "!gamble resolve val")
+	fake.Said()
+
+	// archived voters are never erased
+	fake.send("chan", "admin", "!gamble reset")
+	assert.Equal(t, []string{"A closed vote can not be reset, delete it with '!gamble delete' and create a new one"}, fake.Said())
+	assert.Len(t, g.CurrentVote.Votes, 2)
+	assert.Len(t, g.History.Find(g.CurrentVote.ID).Votes, 2)
+	assert.Equal(t, 1, NewLeaderboard(g.History.Votes).Players["id-alice"].Correct)
+}
+
+func TestHistoryCLI(t *testing.T) {
+	dir := t.TempDir()
+	conf := filepath.Join(dir, "config.yml")
+	assert.Nil(t, ioutil.WriteFile(conf, []byte("storage:\n  dir: "+dir+"\nchannels:\n  - name: Chan\n  - name: other\n"), 0644))
+
+	h, err := NewHistory(filepath.Join(dir, "chan"))
+	assert.Nil(t, err)
+	closed := time.Date(2020, 4, 2, 12, 0, 0, 0, time.UTC)
+	assert.Nil(t, h.Archive(&Vote{ID: "a1", Possibilities: []string{"val", "pl"}, Votes: map[string]string{"alice": "pl"}, ClosedAt: closed}))
+
+	var out bytes.Buffer
+	assert.Nil(t, ListHistory(&out, conf, "chan", 10))
+	assert.Equal(t, "a1 (2020-04-02) val or pl, 1 votes\n", out.String())
+
+	out.Reset()
+	assert.Nil(t, ShowHistory(&out, conf, "CHAN", "a1", "text"))
+	assert.Contains(t, out.String(), "pl (1): alice\n")
+
+	assert.NotNil(t, ShowHistory(&out, conf, "chan", "b2", "text"))
+	assert.NotNil(t, ListHistory(&out, conf, "", 10))
+}
@@ -0,0 +1,83 @@
+package app
+
+import (
+	"sort"
+	"strings"
+
+	twitch "github.com/gempir/go-twitch-irc/v2"
+)
+
+// Voter is a structure containing the names of a voter, as seen when voting
+type Voter struct {
+	// Login, used for whispers and mentions
+	Name string
+	// Name displayed in the channel
+	DisplayName string
+	// Weight of the vote, from roles and subscription tier when voting
+	Weight float64 `json:",omitempty"`
+}
+
+// userID returns the key used to identify a user : its Twitch user ID, which never changes,
+// or its login when the ID is unknown (HTTP API)
+func userID(user twitch.User) string {
+	if user.ID != "" {
+		return user.ID
+	}
+
+	return user.Name
+}
+
+// remember is used to store the names and the vote weight of a voter
+func (v *Vote) remember(user twitch.User, weight float64) {
+	if v.Voters == nil {
+		v.Voters = make(map[string]Voter)
+	}
+
+	v.Voters[userID(user)] = Voter{Name: user.Name, DisplayName: user.DisplayName, Weight: weight}
+}
+
+// name returns the display name of a voter, votes stored by older versions are keyed by name
+func (v *Vote) name(id string) string {
+	voter, ok := v.Voters[id]
+	if !ok {
+		return id
+	}
+
+	if voter.DisplayName != "" {
+		return voter.DisplayName
+	}
+
+	return voter.Name
+}
+
+// login returns the login of a voter, used to whisper
+func (v *Vote) login(id string) string {
+	voter, ok := v.Voters[id]
+	if !ok || voter.Name == "" {
+		return id
+	}
+
+	return voter.Name
+}
+
+// names returns display names of voters, in the same order
+func (v *Vote) names(ids []string) []string {
+	var res []string
+
+	for _, id := range ids {
+		res = append(res, v.name(id))
+	}
+
+	return res
+}
+
+// sortByName is used to sort voters by display name, then by ID
+func (v *Vote) sortByName(ids []string) {
+	sort.Slice(ids, func(i, j int) bool {
+		a, b := strings.ToLower(v.name(ids[i])), strings.ToLower(v.name(ids[j]))
+		if a != b {
+			return a < b
+		}
+		return ids[i] < ids[j]
+	})
+}
@@ -0,0 +1,88 @@
+package app
+
+import (
+	"testing"
+
+	twitch "github.com/gempir/go-twitch-irc/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestVoteAfterRename(t *testing.T) {
+	g, fake := newTestGambling(t, testConf())
+
+	fake.send("chan", "admin", "!gamble create val pl")
+	fake.Said()
+	fake.sendAs("chan", twitch.User{ID: "42", Name: "alice", DisplayName: "Alice"}, "!gamble vote val")
+	// same account, new login
+	fake.sendAs("chan", twitch.User{ID: "42", Name: "alicia", DisplayName: "Alicia"}, "!gamble vote pl")
+
+	assert.Equal(t, map[string]string{"42": "pl"}, g.CurrentVote.Votes)
+	assert.Equal(t, Voter{Name: "alicia", DisplayName: "Alicia", Weight: 1}, g.CurrentVote.Voters["42"])
+
+	fake.send("chan", "admin", "!gamble close")
+	assert.Equal(t, []string{"Vote is now closed, time for statistics ! Participants : 1 | pl : 1 (100.00%)"}, fake.Said())
+
+	// winners are announced using display names and whispered using logins
+	fake.send("chan", "admin", "!gamble roll pl")
+	assert.Equal(t, []string{"@admin  : And... The winner is... Alicia"}, fake.Said())
+	assert.Contains(t, fake.Whispered(), sentMessage{To: "alicia", Message: "Congrat's ! You're the winner ! Contact the streamer to get your reward ! (sent on 2020-04-02)"})
+	assert.Equal(t, []string{"42"}, g.CurrentVote.Winners)
+
+	fake.send("chan", "admin", "!gamble winners")
+	assert.Equal(t, []string{"@admin  : Ordered list of winners for this vote : Alicia"}, fake.Said())
+}
+
+func TestAdminByID(t *testing.T) {
+	conf := testConf()
+	conf.AdminIDs = []string{"1337"}
+	conf.Permissions.DenyIDs = []string{"666"}
+	g, fake := newTestGambling(t, conf)
+
+	// a renamed admin keeps its rights
+	fake.sendAs("chan", twitch.User{ID: "1337", Name: "streamer_v2"}, "!gamble create val pl")
+	assert.True(t, g.CurrentVote.IsOpen)
+
+	// another user taking the login of an admin listed by ID gets nothing
+	fake.sendAs("chan", twitch.User{ID: "7", Name: "1337"}, "!gamble close")
+	assert.True(t, g.CurrentVote.IsOpen)
+
+	fake.sendAs("chan", twitch.User{ID: "666", Name: "troll"}, "!gamble vote val")
+	assert.Empty(t, g.CurrentVote.Votes)
+}
+
+func TestNotifyAdminByID(t *testing.T) {
+	conf := testConf()
+	conf.AdminIDs = []string{"1337"}
+	_, fake := newTestGambling(t, conf)
+
+	fake.send("chan", "admin", "!gamble create val pl")
+	fake.send("chan", "alice", "!gamble vote val")
+	fake.send("chan", "admin", "!gamble close")
+	fake.send("chan", "admin", "!gamble roll pl")
+	fake.Whispered()
+
+	// not seen yet, only admins listed by login are mentioned
+	assert.Equal(t, "@admin  : Sorry not enough candidates to roll a winner in team pl", fake.Said()[2])
+
+	// once seen, the login of an admin listed by ID is mentioned and whispered too
+	fake.sendAs("chan", twitch.User{ID: "1337", Name: "streamer"}, "hello")
+	fake.send("chan", "admin", "!gamble roll val")
+	assert.Equal(t, []string{"@admin @streamer  : And... The winner is... alice"}, fake.Said())
+	assert.Equal(t, []sentMessage{
+		{To: "admin", Message: "Psstt, selected winner is : alice (sent on 2020-04-02)"},
+		{To: "streamer", Message: "Psstt, selected winner is : alice (sent on 2020-04-02)"},
+		{To: "alice", Message: "Congrat's ! You're the winner ! Contact the streamer to get your reward ! (sent on 2020-04-02)"},
+	}, fake.Whispered())
+}
+
+func TestLedgerMovedToID(t *testing.T) {
+	g, fake := newTestGambling(t, testConf())
+
+	// balance stored by login by an older version
+	_, err := g.Ledger.Add("alice", 500)
+	assert.Nil(t, err)
+
+	fake.send("chan", "alice", "!gamble balance")
+	assert.Equal(t, []string{"@alice  : your balance is 1500 points"}, fake.Said())
+	assert.Equal(t, map[string]int64{"id-alice": 1500}, g.Ledger.Balances)
+}
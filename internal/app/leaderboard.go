@@ -0,0 +1,199 @@
+package app
+
+import (
+	"sort"
+	"strconv"
+
+	twitch "github.com/gempir/go-twitch-irc/v2"
+)
+
+// Number of viewers listed by the top command, by default and at most
+const (
+	defaultTop = 5
+	maxTop     = 10
+)
+
+// Player is a structure containing the record of a viewer across all archived votes
+type Player struct {
+	// Twitch user ID
+	ID string `json:"id"`
+	// Last known name
+	Name string `json:"name"`
+	// Votes the viewer took part in
+	Votes int `json:"votes"`
+	// Resolved votes the viewer took part in
+	Predictions int `json:"predictions"`
+	// Resolved votes the viewer predicted correctly
+	Correct int `json:"correct"`
+	// Rolls the viewer won, forfeited prizes excepted
+	Wins int `json:"wins"`
+	// Correct predictions in a row, up to the last resolved vote of the viewer
+	Streak int `json:"streak"`
+	// Longest streak
+	BestStreak int `json:"best_streak"`
+}
+
+// Accuracy returns the percent of correct predictions
+func (p *Player) Accuracy() float64 {
+	if p.Predictions == 0 {
+		return 0
+	}
+
+	return float64(p.Correct) / float64(p.Predictions) * 100
+}
+
+// Leaderboard is a structure containing the record of each viewer, by Twitch user ID
+type Leaderboard struct {
+	Players map[string]*Player
+}
+
+// NewLeaderboard is used to build a leaderboard from votes, oldest first
+// viewers are followed using their Twitch user ID, or their login for votes stored by older versions
+func NewLeaderboard(votes []*Vote) *Leaderboard {
+	l := &Leaderboard{Players: make(map[string]*Player)}
+
+	for _, v := range votes {
+		for u := range v.Votes {
+			p := l.player(v, u)
+			p.Votes++
+
+			if v.Outcome == "" {
+				continue
+			}
+
+			p.Predictions++
+			if v.picked(u, v.Outcome) {
+				p.Correct++
+				p.Streak++
+				if p.Streak > p.BestStreak {
+					p.BestStreak = p.Streak
+				}
+			} else {
+				p.Streak = 0
+			}
+		}
+
+		for _, w := range v.Winners {
+			// forfeited prizes are not won
+			if v.wonBy(w) {
+				l.player(v, w).Wins++
+			}
+		}
+	}
+
+	return l
+}
+
+// player returns the record of a voter, created if needed
+func (l *Leaderboard) player(v *Vote, id string) *Player {
+	p, ok := l.Players[id]
+	if !ok {
+		p = &Player{ID: id}
+		l.Players[id] = p
+	}
+	// votes are read oldest first, so the last name wins
+	p.Name = v.name(id)
+
+	return p
+}
+
+// Top returns the n best predictors : most correct predictions, then best accuracy, then most wins
+func (l *Leaderboard) Top(n int) []*Player {
+	var res []*Player
+	for _, p := range l.Players {
+		if p.Correct > 0 || p.Wins > 0 {
+			res = append(res, p)
+		}
+	}
+
+	sort.Slice(res, func(i, j int) bool {
+		a, b := res[i], res[j]
+		if a.Correct != b.Correct {
+			return a.Correct > b.Correct
+		}
+		if a.Accuracy() != b.Accuracy() {
+			return a.Accuracy() > b.Accuracy()
+		}
+		if a.Wins != b.Wins {
+			return a.Wins > b.Wins
+		}
+		return a.Name < b.Name
+	})
+
+	if len(res) > n {
+		res = res[:n]
+	}
+
+	return res
+}
+
+// leaderboard is used to build the leaderboard of the channel from archived votes
+func (g *Gambling) leaderboard() *Leaderboard {
+	g.History.mutex.Lock()
+	defer g.History.mutex.Unlock()
+
+	return NewLeaderboard(g.History.Votes)
+}
+
+// handle a call to the best predictors of the channel
+func (g *Gambling) handleTop(user twitch.User, args []string) {
+
+	n := defaultTop
+	if len(args) > 0 {
+		c, err := strconv.Atoi(args[0])
+		if err != nil || c <= 0 {
+			g.sayAt(g.text("top.usage", nil), []string{user.Name})
+			return
+		}
+		n = c
+	}
+	if n > maxTop {
+		n = maxTop
+	}
+
+	top := g.leaderboard().Top(n)
+	if len(top) == 0 {
+		g.say(g.text("top.none", nil))
+		return
+	}
+
+	var players []string
+	for i, p := range top {
+		players = append(players, g.text("top.player", vars{
+			"Rank":        i + 1,
+			"Name":        p.Name,
+			"Correct":     p.Correct,
+			"Predictions": p.Predictions,
+			"Accuracy":    p.Accuracy(),
+			"Wins":        p.Wins,
+		}))
+	}
+
+	g.say(g.text("top", vars{"Players": players}))
+}
+
+// handle a call to the record of a viewer
+func (g *Gambling) handleMe(user twitch.User, args []string) {
+
+	l := g.leaderboard()
+
+	p, ok := l.Players[userID(user)]
+	if !ok {
+		// votes stored by older versions only know logins
+		p, ok = l.Players[user.Name]
+	}
+	if !ok {
+		g.sayAt(g.text("me.none", nil), []string{user.Name})
+		return
+	}
+
+	g.sayAt(g.text("me", vars{
+		"Votes":       p.Votes,
+		"Correct":     p.Correct,
+		"Predictions": p.Predictions,
+		"Accuracy":    p.Accuracy(),
+		"Wins":        p.Wins,
+		"Streak":      p.Streak,
+		"Best":        p.BestStreak,
+	}), []string{user.Name})
+}
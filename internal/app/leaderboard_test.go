@@ -0,0 +1,105 @@
+package app
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLeaderboard(t *testing.T) {
+	alice := map[string]Voter{"1": {Name: "alice"}, "2": {Name: "bob"}, "3": {Name: "carol"}}
+	// alice is now alicia
+	alicia := map[string]Voter{"1": {Name: "alicia", DisplayName: "Alicia"}, "2": {Name: "bob"}, "3": {Name: "carol"}}
+
+	votes := []*Vote{
+		{
+			ID:      "1",
+			Votes:   map[string]string{"1": "a", "2": "b", "3": "a"},
+			Voters:  alice,
+			Outcome: "a",
+			Winners: []string{"3"},
+		},
+		// not resolved, only participation counts
+		{
+			ID:     "2",
+			Votes:  map[string]string{"1": "a", "2": "a"},
+			Voters: alice,
+		},
+		{
+			ID:      "3",
+			Votes:   map[string]string{"1": "b", "2": "b", "3": "a"},
+			Voters:  alicia,
+			Outcome: "b",
+		},
+		{
+			ID:      "4",
+			Votes:   map[string]string{"1": "b", "3": "a"},
+			Voters:  alicia,
+			Outcome: "b",
+		},
+	}
+
+	l := NewLeaderboard(votes)
+
+	player := l.Players["1"]
+	assert.Equal(t, "Alicia", player.Name)
+	assert.Equal(t, 4, player.Votes)
+	assert.Equal(t, 3, player.Predictions)
+	assert.Equal(t, 3, player.Correct)
+	assert.Equal(t, 3, player.Streak)
+	assert.Equal(t, 100.0, player.Accuracy())
+
+	bob := l.Players["2"]
+	assert.Equal(t, 1, bob.Correct)
+	assert.Equal(t, 1, bob.Streak)
+
+	carol := l.Players["3"]
+	assert.Equal(t, 1, carol.Correct)
+	assert.Equal(t, 1, carol.Wins)
+	assert.Equal(t, 0, carol.Streak)
+	assert.Equal(t, 1, carol.BestStreak)
+
+	top := l.Top(2)
+	assert.Len(t, top, 2)
+	assert.Equal(t, "Alicia", top[0].Name)
+	// bob and carol made one correct prediction, bob has a better accuracy
+	assert.Equal(t, "bob", top[1].Name)
+	assert.Equal(t, "carol", l.Top(3)[2].Name)
+}
+
+func TestLeaderboardOlderVotes(t *testing.T) {
+	// votes stored by older versions are keyed by login
+	l := NewLeaderboard([]*Vote{{Votes: map[string]string{"alice": "a"}, Outcome: "a"}})
+
+	assert.Equal(t, 1, l.Players["alice"].Correct)
+}
+
+func TestLeaderboardCommands(t *testing.T) {
+	_, fake := newTestGambling(t, testConf())
+
+	fake.send("chan", "alice", "!gamble top")
+	assert.Equal(t, []string{"Nobody made a correct prediction yet"}, fake.Said())
+
+	fake.send("chan", "alice", "!gamble me")
+	assert.Equal(t, []string{"@alice  : you did not take part in any vote yet"}, fake.Said())
+
+	for _, outcome := range []string{"val", "pl"} {
+		fake.send("chan", "admin", "!gamble create val pl")
+		fake.send("chan", "alice", "!gamble vote val")
+		fake.send("chan", "bob", "!gamble vote pl")
+		fake.send("chan", "admin", "!gamble close")
+		fake.send("chan", "admin", "!gamble resolve "+outcome)
+		fake.send("chan", "admin", "!gamble roll "+outcome)
+		fake.send("chan", "admin", "!gamble delete")
+	}
+	fake.Said()
+
+	fake.send("chan", "carol", "!gamble top")
+	assert.Equal(t, []string{"Best predictors : 1. alice 1/2 (50%), 1 wins | 2. bob 1/2 (50%), 1 wins"}, fake.Said())
+
+	fake.send("chan", "bob", "!gamble me")
+	assert.Equal(t, []string{"@bob  : 2 votes, 1/2 correct predictions (50%), 1 wins, current streak 1 (best 1)"}, fake.Said())
+
+	fake.send("chan", "bob", "!gamble top nope")
+	assert.Equal(t, []string{"@bob  : You must specify a number of viewers (example : '!gamble top 3')"}, fake.Said())
+}
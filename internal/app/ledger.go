@@ -0,0 +1,141 @@
+package app
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// ledgerFile is the name of the file used to store viewers balances
+const ledgerFile = "ledger.json"
+
+// ErrInsufficientFunds is returned when a balance can not cover a debit
+var ErrInsufficientFunds = errors.New("Insufficient funds")
+
+// Ledger is a structure containing the virtual currency balance of each viewer
+type Ledger struct {
+	mutex sync.Mutex
+	// File used to persist balances, memory only if empty
+	path string
+	// Balance given to a viewer seen for the first time
+	initial int64
+	// Balances, by user
+	Balances map[string]int64
+}
+
+// NewLedger is used to init a Ledger, loading balances stored inside dir
+// if dir is empty, balances are kept in memory only
+func NewLedger(dir string, initial int64) (*Ledger, error) {
+	l := &Ledger{
+		initial:  initial,
+		Balances: make(map[string]int64),
+	}
+
+	if dir == "" {
+		return l, nil
+	}
+
+	err := os.MkdirAll(dir, 0755)
+	if err != nil {
+		return nil, err
+	}
+
+	l.path = filepath.Join(dir, ledgerFile)
+
+	data, err := ioutil.ReadFile(l.path)
+	if os.IsNotExist(err) {
+		return l, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	err = json.Unmarshal(data, &l.Balances)
+	if err != nil {
+		return nil, err
+	}
+
+	return l, nil
+}
+
+// Balance returns the balance of a user
+func (l *Ledger) Balance(user string) int64 {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	return l.balance(user)
+}
+
+// balance returns the balance of a user, the initial one if the user is unknown
+func (l *Ledger) balance(user string) int64 {
+	b, ok := l.Balances[user]
+	if !ok {
+		return l.initial
+	}
+
+	return b
+}
+
+// Add credits (or debits, if amount is negative) a user balance and returns the new balance
+func (l *Ledger) Add(user string, amount int64) (int64, error) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	b := l.balance(user) + amount
+	if b < 0 {
+		return l.balance(user), ErrInsufficientFunds
+	}
+
+	previous, ok := l.Balances[user]
+	l.Balances[user] = b
+
+	// a balance which is not persisted would be lost after a restart
+	err := l.save()
+	if err != nil {
+		if ok {
+			l.Balances[user] = previous
+		} else {
+			delete(l.Balances, user)
+		}
+		return l.balance(user), err
+	}
+
+	return b, nil
+}
+
+// Rename moves the balance of a user to a new key, unless the new key already has one
+// used to move balances stored by login to Twitch user IDs
+func (l *Ledger) Rename(from string, to string) error {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	b, ok := l.Balances[from]
+	if !ok || from == to {
+		return nil
+	}
+	if _, ok := l.Balances[to]; ok {
+		return nil
+	}
+
+	l.Balances[to] = b
+	delete(l.Balances, from)
+
+	return l.save()
+}
+
+// save writes balances into the ledger file, if any
+func (l *Ledger) save() error {
+	if l.path == "" {
+		return nil
+	}
+
+	data, err := json.Marshal(l.Balances)
+	if err != nil {
+		return err
+	}
+
+	return writeFileAtomic(l.path, data)
+}
@@ -0,0 +1,57 @@
+package app
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLedger(t *testing.T) {
+	dir := t.TempDir()
+
+	l, err := NewLedger(dir, 100)
+	assert.Nil(t, err)
+
+	assert.Equal(t, int64(100), l.Balance("alice"))
+
+	b, err := l.Add("alice", -40)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(60), b)
+
+	b, err = l.Add("alice", -61)
+	assert.Equal(t, ErrInsufficientFunds, err)
+	assert.Equal(t, int64(60), b)
+
+	_, err = l.Add("bob", 50)
+	assert.Nil(t, err)
+
+	// balances are persisted
+	l, err = NewLedger(dir, 100)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(60), l.Balance("alice"))
+	assert.Equal(t, int64(150), l.Balance("bob"))
+	assert.Equal(t, int64(100), l.Balance("carol"))
+
+	// balances are unchanged when they can not be persisted
+	assert.Nil(t, os.RemoveAll(dir))
+
+	b, err = l.Add("alice", -10)
+	assert.NotNil(t, err)
+	assert.NotEqual(t, ErrInsufficientFunds, err)
+	assert.Equal(t, int64(60), b)
+	assert.Equal(t, int64(60), l.Balance("alice"))
+
+	_, err = l.Add("carol", -10)
+	assert.NotNil(t, err)
+	assert.Equal(t, map[string]int64{"alice": 60, "bob": 150}, l.Balances)
+}
+
+func TestMemoryLedger(t *testing.T) {
+	l, err := NewLedger("", 10)
+	assert.Nil(t, err)
+
+	b, err := l.Add("alice", 5)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(15), b)
+}
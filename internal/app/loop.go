@@ -0,0 +1,25 @@
+package app
+
+// The vote state of a Gambling instance is owned by a single goroutine running its event loop.
+// Everything touching it from another goroutine (chat messages, timers, HTTP API, overlays)
+// must go through do (timers send their action to the loop directly, see after), handlers run on
+// the loop goroutine and must never call do themselves.
+
+// loop runs actions sent to the Gambling instance, one at a time
+func (g *Gambling) loop() {
+	for action := range g.actions {
+		action()
+	}
+}
+
+// do runs an action on the loop goroutine and waits for it
+func (g *Gambling) do(action func()) {
+	done := make(chan struct{})
+
+	g.actions <- func() {
+		defer close(done)
+		action()
+	}
+
+	<-done
+}
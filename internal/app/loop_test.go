@@ -0,0 +1,122 @@
+package app
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"runtime"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDo(t *testing.T) {
+	g, _ := newTestGambling(t, testConf())
+
+	var open bool
+	g.do(func() {
+		g.handleCreate(apiUser, []string{"val", "pl"})
+		open = g.CurrentVote.IsOpen
+	})
+
+	assert.True(t, open)
+}
+
+// TestConcurrentCommands hammers a bot from chat, timers and the HTTP API at once,
+// meant to be run with the race detector
+func TestConcurrentCommands(t *testing.T) {
+	conf := testConf()
+	conf.API.Token = "secret"
+	conf.Storage.Dir = t.TempDir()
+	b, fake := newTestBot(t, conf)
+
+	g := b.Sessions["chan"]
+
+	fake.send("chan", "admin", "!gamble create val pl")
+
+	sub := g.Events.Subscribe()
+	defer g.Events.Unsubscribe(sub)
+
+	wait := sync.WaitGroup{}
+
+	// viewers
+	for i := 0; i < 10; i++ {
+		wait.Add(1)
+		go func(i int) {
+			defer wait.Done()
+			user := fmt.Sprintf("viewer%d", i)
+			for j := 0; j < 50; j++ {
+				fake.send("chan", user, "!gamble vote val")
+				fake.send("chan", user, "!gamble bet 10")
+				fake.send("chan", user, "!gamble balance")
+			}
+		}(i)
+	}
+
+	// admin, with timed votes closing by themselves
+	wait.Add(1)
+	go func() {
+		defer wait.Done()
+		for j := 0; j < 20; j++ {
+			fake.send("chan", "admin", "!gamble create 1ms val pl")
+			fake.send("chan", "admin", "!gamble extend 1ms")
+			// every other vote is left to its timer
+			if j%2 == 0 {
+				fake.send("chan", "admin", "!gamble close")
+			}
+			fake.send("chan", "admin", "!gamble resolve val")
+			fake.send("chan", "admin", "!gamble roll val")
+			fake.send("chan", "admin", "!gamble reset")
+			fake.send("chan", "admin", "!gamble delete")
+		}
+	}()
+
+	// time goes by until everyone is done, timed votes close by themselves
+	done := make(chan struct{})
+	go func() {
+		for {
+			select {
+			case <-done:
+				return
+			default:
+				clockOf(g).Advance(time.Second)
+				runtime.Gosched()
+			}
+		}
+	}()
+
+	// HTTP API
+	wait.Add(1)
+	go func() {
+		defer wait.Done()
+		for j := 0; j < 20; j++ {
+			apiCall(t, b, http.MethodGet, "/api/channels/chan/vote", "", "secret")
+			apiCall(t, b, http.MethodPost, "/api/channels/chan/vote/create", `{"args": ["5ms", "val", "pl"]}`, "secret")
+			apiCall(t, b, http.MethodPost, "/api/channels/chan/vote/close", "", "secret")
+		}
+	}()
+
+	// overlay subscriber
+	go func() {
+		for {
+			select {
+			case event := <-sub:
+				_ = event.State.Tallies
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	wait.Wait()
+	close(done)
+
+	// the loop is still alive and consistent
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/api/channels/chan/vote", nil)
+	req.Header.Set("Authorization", "Bearer secret")
+	b.apiHandler().ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusOK, rec.Code)
+}
@@ -0,0 +1,322 @@
+package app
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"text/template"
+
+	"github.com/apex/log"
+	"gopkg.in/yaml.v2"
+)
+
+// vars is a structure containing the named placeholders of a message
+type vars map[string]interface{}
+
+// defaultMessages are the templates of messages sent by the bot, by name, using Go text/template syntax
+// messages of a locale or from the config replace them, missing ones keep these defaults
+var defaultMessages = map[string]string{
+	// sent once connected, the hello message of the config if not empty
+	"hello":       "",
+	"unsupported": "Sorry but this is not a supported command",
+	"rate_limit":  "Warning, whisper rate limit reached, you may receive your vote acknowledgement later",
+	"not_closed":  "Hey ! The vote isn't closed ! Close it using command : '{{.Prefix}} close'",
+	"more":        "{{.List}} (and {{.More}} more)",
+
+	// choices of a vote
+	"choices":        `{{join .Possibilities " or "}}`,
+	"choices.range":  "a number between {{.Min}} and {{.Max}}",
+	"choices.number": "a number",
+
+	// how to vote, by vote mode
+	"usage.single": "You can vote with '{{.Prefix}} vote <vote> (choices are : {{.Choices}})'",
+	"usage.multi":  "You can vote for up to {{.Max}} choices with '{{.Prefix}} vote <vote> <vote> ... (choices are : {{.Choices}})'",
+	"usage.ranked": "You can rank choices from favorite to least favorite with '{{.Prefix}} vote <first> <second> ... (choices are : {{.Choices}})'",
+	"usage.number": "You can guess with '{{.Prefix}} vote <number> (choices are : {{.Choices}})'",
+
+	// vote life cycle
+	"create":          "There is a new vote! {{.Usage}}{{with .Audience}}, reserved to {{.}}{{end}}{{if .Duration}}, it will close automatically in {{.Duration}}{{end}}",
+	"create.open":     "There is already a vote going, you should delete it first with '{{.Prefix}} delete'.",
+	"create.range":    "You need to pass the range as two numbers, or nothing (example : '{{.Prefix}} create number 0 10')",
+	"create.choices":  "You need to pass the choices as arguments (2 at least)",
+	"restored.open":   "I'm back ! The vote is still open with {{.Total}} votes, you can vote with '{{.Prefix}} vote <vote> (choices are : {{.Choices}})'",
+	"restored.closed": "I'm back ! The last vote (choices were : {{.Choices}}) is closed with {{.Total}} votes and {{.Winners}} winners",
+	"timer.reminder":  "Hurry up ! Only {{.Left}} left to vote with '{{.Prefix}} vote <vote>' (choices are : {{.Choices}})",
+	"extend":          "Vote extended by {{.Extension}}, it will close automatically in {{.Remaining}}",
+	"extend.none":     "There is no timed vote to extend",
+	"extend.usage":    "You need to pass a valid duration as argument (example : '{{.Prefix}} extend 1m')",
+	"cancel":          "Timer cancelled, the vote will stay open until closed with '{{.Prefix}} close'",
+	"cancel.none":     "There is no timer to cancel",
+	"close":           "Vote is now closed, time for statistics ! {{.Summary}}",
+	"close.closed":    "Do not try to close an alreay closed vote !",
+	"close.seed":      "Winners will be drawn using a seed whose SHA-256 hash is {{.Hash}}",
+	"delete":          "Vote deleted !",
+	"reset.closed":    "A closed vote can not be reset, delete it with '{{.Prefix}} delete' and create a new one",
+
+	// who can vote
+	"audience":       `{{join .Parts " and "}}`,
+	"audience.roles": `{{join .Roles ", "}} viewers`,
+	"audience.tier":  "tier {{.Tier}} subscribers and above",
+	"audience.subs":  "subscribers",
+
+	// acknowledgements, whispered
+	"vote.ack":        "For your information, I correctly handled your vote for {{.Vote}} (sent on {{.Date}})",
+	"vote.invalid":    "Sorry but the vote command you send is not valid, you may have made a mistake, please retry (sent on {{.Date}})",
+	"vote.rejected":   "Sorry but {{.Reason}} (sent on {{.Date}})",
+	"reject.ignored":  "you are excluded from votes in this channel",
+	"reject.reserved": "this vote is reserved to {{.Audience}}",
+
+	// statistics announced on close
+	"stats":          `Participants : {{.Total}} | {{join .Tallies ", "}}{{.Weighted}}`,
+	"stats.total":    "Participants : {{.Total}}",
+	"stats.tally":    `{{.Choice}} : {{.Votes}} ({{printf "%.2f" .Percent}}%)`,
+	"stats.ranked":   `Participants : {{.Total}} | {{join .Parts " | "}}`,
+	"stats.round":    `Round {{.Round}} : {{join .Tallies ", "}}`,
+	"stats.winner":   "Winner : {{.Winner}}",
+	"stats.tie":      `Tie between {{join .Tied " and "}}`,
+	"stats.weighted": ` | Weighted : {{join .Tallies ", "}}`,
+	"stats.weight":   `{{.Choice}} : {{.Weight}} ({{printf "%.2f" .Percent}}%)`,
+	"stats.number":   "Participants : {{.Total}} | min : {{.Min}}, max : {{.Max}}, mean : {{.Mean}}, median : {{.Median}} | {{.Distribution}}",
+	"stats.format":   "Unknown statistics format {{.Format}} (formats are : text, json or csv)",
+	"stats.error":    "Error generating statistics",
+	"stats.private":  "Statistics generated in private mode",
+
+	// resolution and bets
+	"resolve":              "The result is {{.Outcome}} !{{if .Correct}} {{.Count}} viewers guessed right : {{.Correct}}{{else}} Nobody guessed right{{end}}{{.Bets}}",
+	"resolve.number":       "The result is {{.Outcome}} !{{if .Closest}} Closest guesses : {{.Closest}}{{if .Ties}} (ties included){{end}}{{else}} Nobody made a guess{{end}}{{.Bets}}",
+	"resolve.guess":        "{{.Name}} ({{.Value}})",
+	"resolve.none":         "There is no vote to resolve",
+	"resolve.resolved":     "This vote is already resolved, the result was {{.Outcome}}",
+	"resolve.usage":        "You must specify a valid result (choices are : {{.Choices}})",
+	"resolve.number_usage": "You must specify the result as a number (example : '{{.Prefix}} resolve 42')",
+	"bets.shared":          " | {{.Winners}} winning bets share a pool of {{.Pool}} {{.Currency}}",
+	"bets.refunded":        " | Nobody bet on it, all bets are refunded",
+	"bet":                  "Your bet of {{.Amount}} {{.Currency}} on {{.Choice}} is registered, your balance is now {{.Balance}} {{.Currency}} (sent on {{.Date}})",
+	"bet.usage":            "Sorry but the bet command you send is not valid, use '{{.Prefix}} bet <choice> <amount>' (sent on {{.Date}})",
+	"bet.choice":           "Sorry but {{.Choice}} is not a valid choice (choices are : {{.Choices}}) (sent on {{.Date}})",
+	"bet.amount":           "Sorry but {{.Amount}} is not a valid amount of {{.Currency}} (sent on {{.Date}})",
+	"bet.funds":            "Sorry but you only have {{.Balance}} {{.Currency}} (sent on {{.Date}})",
+	"bet.ballot":           "Sorry but you already picked {{.Max}} choices, vote again with {{.Choice}} to bet on it (sent on {{.Date}})",
+	"bet.error":            "Sorry but your bet could not be registered, please retry later (sent on {{.Date}})",
+	"bet.won":              "Well done ! You won {{.Gain}} {{.Currency}}, your balance is now {{.Balance}} {{.Currency}} (sent on {{.Date}})",
+	"balance":              "your balance is {{.Balance}} {{.Currency}}",
+
+	// rolls
+	"roll.winner":         "And... The winner is... {{.Winner}}{{.Claim}}",
+	"roll.winners":        "And... The winners are... {{.Winners}}{{if .Short}} (no more candidates){{end}}{{.Claim}}",
+	"roll.claim":          " ! Type '{{.Prefix}} claim' within {{.Timeout}} to get your reward",
+	"roll.none":           "You can not roll since there is no vote",
+	"roll.usage":          "You must specify the winner (choices are : {{.Choices}} or {{.All}})",
+	"roll.invalid":        "{{.Team}} is not a correct roll option (choices are : {{.Choices}} or {{.All}})",
+	"roll.count":          "You must specify a number of winners between 1 and {{.Max}} (example : '{{.Prefix}} roll {{.Team}} 3')",
+	"roll.resolved":       "The vote is resolved, you can only roll a winner among {{.Outcome}} voters",
+	"roll.lost":           "{{.Team}} lost, you can only roll a winner among {{.Outcome}} voters",
+	"roll.empty":          "Sorry not enough candidates to roll a winner in team {{.Team}}",
+	"roll.empty_all":      "Sorry not enough candidates to roll a winner among voters",
+	"roll.seed":           "Draw seed of vote {{.Vote}} : {{.Seed}}",
+	"roll.admin":          "Psstt, selected winner is : {{.Winner}} (sent on {{.Date}})",
+	"roll.admin_many":     "Psstt, selected winners are : {{.Winners}} (sent on {{.Date}})",
+	"roll.congrats":       "Congrat's ! You're the winner ! Contact the streamer to get your reward ! (sent on {{.Date}})",
+	"roll.congrats_claim": "Congrat's ! You're the winner ! Type '{{.Prefix}} claim' in the chat within {{.Timeout}} to get your reward ! (sent on {{.Date}})",
+	"winners":             `Ordered list of winners for this vote : {{join .Winners " - "}}`,
+	"winners.none":        "There is no selected winners for this vote",
+	"winners.claimed":     "{{.Winner}} (claimed)",
+	"winners.forfeit":     "{{.Winner}} (forfeit)",
+
+	// claims and rerolls
+	"claim":           "your prize is claimed, the streamer will contact you",
+	"claim.code":      "your prize is claimed, your code was sent by whisper",
+	"claim.none":      "you have no prize to claim",
+	"claim.claimed":   "you already claimed your prize",
+	"claim.expired":   "{{.Winner}} did not claim the prize in time, use '{{.Prefix}} reroll {{.Login}}' to select another winner",
+	"reroll":          "{{.Winner}} forfeited the prize ! And... The new winner is... {{.New}}{{.Claim}}",
+	"reroll.failed":   "{{.Winner}} forfeited the prize. {{.Error}}",
+	"reroll.unknown":  "{{.Name}} is not a winner of this vote",
+	"reroll.none":     "There is no winner left to reroll",
+	"reroll.claimed":  "{{.Winner}} already claimed the prize",
+	"reroll.replaced": "{{.Winner}} already forfeited and was replaced",
+
+	// prizes
+	"prize.code":     "Congrat's ! You won {{.Prize}}, here is your code : {{.Code}} (sent on {{.Date}})",
+	"prize.failed":   "{{.Prize}} could not be delivered to {{.Winner}}, deliver it manually ({{.Error}})",
+	"prize.manual":   "{{.Prize}} must be delivered manually to {{.Winners}}, prize codes can not be whispered without verified account",
+	"prize.stock":    `Prizes : {{join .Stock ", "}}{{with .Prize}} | Prize of this vote : {{.}}{{end}}`,
+	"prize.left":     "{{.Prize}} ({{.Left}} left)",
+	"prize.attached": "Winners of this vote will receive a code of {{.Prize}} by whisper ({{.Left}} left)",
+	"prize.detached": "No prize is attached to this vote anymore",
+	"prize.unknown":  `{{.Prize}} is not a prize (prizes are : {{join .Prizes ", "}})`,
+	"prize.none":     "There is no prize catalogue",
+	"prize.vote":     "There is no vote to attach a prize to",
+
+	// history and leaderboard
+	"history":       `Last {{.Count}} votes : {{join .Votes " | "}}`,
+	"history.vote":  `{{.ID}} ({{.Closed}}) {{.Choices}}, {{.Total}} votes{{with .Outcome}}, result {{.}}{{end}}{{with .Winners}}, winners {{join . " - "}}{{end}}`,
+	"history.none":  "There is no past vote",
+	"history.usage": "You must specify a number of votes (example : '{{.Prefix}} history 5')",
+	"top":           `Best predictors : {{join .Players " | "}}`,
+	"top.player":    `{{.Rank}}. {{.Name}} {{.Correct}}/{{.Predictions}} ({{printf "%.0f" .Accuracy}}%), {{.Wins}} wins`,
+	"top.none":      "Nobody made a correct prediction yet",
+	"top.usage":     "You must specify a number of viewers (example : '{{.Prefix}} top 3')",
+	"me":            `{{.Votes}} votes, {{.Correct}}/{{.Predictions}} correct predictions ({{printf "%.0f" .Accuracy}}%), {{.Wins}} wins, current streak {{.Streak}} (best {{.Best}})`,
+	"me.none":       "you did not take part in any vote yet",
+}
+
+// templateFuncs are the functions usable by message templates
+var templateFuncs = template.FuncMap{
+	"join": strings.Join,
+}
+
+// Catalogue is a structure containing the templates of messages sent by the bot, by name
+type Catalogue struct {
+	templates map[string]*template.Template
+}
+
+// defaultCatalogue contains the default messages, used as fallback and outside of a channel (CLI)
+var defaultCatalogue = mustCatalogue(defaultMessages)
+
+// mustCatalogue is used to parse templates, panics if one of them is not valid
+func mustCatalogue(messages map[string]string) *Catalogue {
+	c := &Catalogue{templates: make(map[string]*template.Template)}
+
+	for name, text := range messages {
+		t, err := template.New(name).Funcs(templateFuncs).Parse(text)
+		if err != nil {
+			panic(fmt.Sprintf("Error parsing message %s : %s", name, err))
+		}
+		c.templates[name] = t
+	}
+
+	return c
+}
+
+// NewCatalogue is used to load the messages of a channel : default messages, replaced by the
+// messages of its locale (<dir>/<locale>.yml), then by the hello message, then by messages of the config
+// messages which can not be loaded or parsed are logged and keep their default
+func NewCatalogue(conf Conf) *Catalogue {
+	c := &Catalogue{templates: make(map[string]*template.Template)}
+	for name, t := range defaultCatalogue.templates {
+		c.templates[name] = t
+	}
+
+	if conf.Messages.Locale != "" {
+		messages, err := loadLocale(conf.Messages.Dir, conf.Messages.Locale)
+		if err != nil {
+			log.WithError(err).WithField("locale", conf.Messages.Locale).Error("Error loading messages, using default ones")
+		}
+		c.set(messages)
+	}
+
+	if conf.Hello != "" {
+		c.set(map[string]string{"hello": conf.Hello})
+	}
+
+	c.set(conf.Messages.Templates)
+
+	return c
+}
+
+// loadLocale is used to read the messages of a locale, by name
+func loadLocale(dir string, locale string) (map[string]string, error) {
+	data, err := ioutil.ReadFile(filepath.Join(dir, strings.ToLower(locale)+".yml"))
+	if err != nil {
+		return nil, err
+	}
+
+	var messages map[string]string
+	err = yaml.Unmarshal(data, &messages)
+	if err != nil {
+		return nil, err
+	}
+
+	return messages, nil
+}
+
+// set is used to replace templates, unknown or invalid ones are ignored
+func (c *Catalogue) set(messages map[string]string) {
+	for name, text := range messages {
+		if _, ok := defaultMessages[name]; !ok {
+			log.WithField("message", name).Warn("Unknown message in catalogue")
+			continue
+		}
+
+		t, err := template.New(name).Funcs(templateFuncs).Parse(text)
+		if err != nil {
+			log.WithError(err).WithField("message", name).Error("Error parsing message, using default one")
+			continue
+		}
+
+		c.templates[name] = t
+	}
+}
+
+// render is used to generate a message, the default message is used if the template fails
+func (c *Catalogue) render(name string, data vars) string {
+	var buf bytes.Buffer
+
+	t, ok := c.templates[name]
+	if !ok {
+		log.WithField("message", name).Error("Unknown message")
+		return name
+	}
+
+	err := t.Execute(&buf, data)
+	if err == nil {
+		return buf.String()
+	}
+
+	log.WithError(err).WithField("message", name).Error("Error generating message, using default one")
+
+	if c == defaultCatalogue {
+		return name
+	}
+
+	return defaultCatalogue.render(name, data)
+}
+
+// truncate is used to list at most max users, max <= 0 means no limit
+func (c *Catalogue) truncate(users []string, max int) string {
+	if max <= 0 || len(users) <= max {
+		return strings.Join(users, ", ")
+	}
+
+	return c.render("more", vars{"List": strings.Join(users[:max], ", "), "More": len(users) - max})
+}
+
+// tally returns the votes of a choice, with its percentage
+func (c *Catalogue) tally(t Tally) string {
+	return c.render("stats.tally", vars{"Choice": t.Choice, "Votes": t.Votes, "Percent": t.Percent})
+}
+
+// choices returns all possibilities of a vote as a string, or its range for a numeric vote
+func (c *Catalogue) choices(v *Vote) string {
+	if v.mode() != modeNumber {
+		return c.render("choices", vars{"Possibilities": v.Possibilities})
+	}
+
+	if v.Min != nil && v.Max != nil {
+		return c.render("choices.range", vars{"Min": formatNumber(*v.Min), "Max": formatNumber(*v.Max)})
+	}
+
+	return c.render("choices.number", nil)
+}
+
+// text is used to generate a message of the channel, common placeholders are added to the given ones :
+// Prefix, Channel, Choices, Currency and Date (of the day, used as tail of private messages)
+func (g *Gambling) text(name string, v vars) string {
+	date := now()
+
+	data := vars{
+		"Prefix":   g.Config.Prefix,
+		"Channel":  g.Config.Twitch.Channel,
+		"Choices":  g.choices(),
+		"Currency": g.Config.Wallet.Currency,
+		"Date":     fmt.Sprintf("%d-%02d-%02d", date.Year(), date.Month(), date.Day()),
+	}
+	for k, val := range v {
+		data[k] = val
+	}
+
+	return g.Messages.render(name, data)
+}
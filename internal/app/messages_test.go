@@ -0,0 +1,88 @@
+package app
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+	"text/template"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewCatalogue(t *testing.T) {
+	dir := t.TempDir()
+	assert.Nil(t, ioutil.WriteFile(filepath.Join(dir, "fr.yml"), []byte(
+		"delete: \"Vote supprimé !\"\n"+
+			"unsupported: \"Commande inconnue\"\n"+
+			"cancel: \"{{.Prefix\"\n"+
+			"nope: \"ignored\"\n"), 0644))
+
+	conf := testConf()
+	conf.Hello = "Salut {{.Channel}}"
+	conf.Messages = Messages{
+		Locale:    "FR",
+		Dir:       dir,
+		Templates: map[string]string{"unsupported": "Commande inconnue sur {{.Channel}}"},
+	}
+	c := NewCatalogue(conf)
+
+	// locale messages replace default ones
+	assert.Equal(t, "Vote supprimé !", c.render("delete", nil))
+	// messages of the config replace locale ones
+	assert.Equal(t, "Commande inconnue sur chan", c.render("unsupported", vars{"Channel": "chan"}))
+	// the hello message of the config is a template too
+	assert.Equal(t, "Salut chan", c.render("hello", vars{"Channel": "chan"}))
+	// invalid messages keep the default one
+	assert.Equal(t, "Timer cancelled, the vote will stay open until closed with '!gamble close'", c.render("cancel", vars{"Prefix": "!gamble"}))
+	_, ok := c.templates["nope"]
+	assert.False(t, ok)
+
+	// defaults are kept if the locale can not be loaded
+	conf.Messages = Messages{Locale: "de", Dir: dir}
+	c = NewCatalogue(conf)
+	assert.Equal(t, "Vote deleted !", c.render("delete", nil))
+}
+
+func TestRenderFallback(t *testing.T) {
+	conf := testConf()
+	conf.Messages.Templates = map[string]string{"balance": "{{.Balance.Nope}} {{.Currency}}"}
+	c := NewCatalogue(conf)
+
+	// a message failing to execute is replaced by the default one
+	assert.Equal(t, "your balance is 42 points", c.render("balance", vars{"Balance": 42, "Currency": "points"}))
+	assert.Equal(t, "alice, bob (and 1 more)", c.truncate([]string{"alice", "bob", "carol"}, 2))
+}
+
+func TestFrenchLocale(t *testing.T) {
+	messages, err := loadLocale("../../messages", "fr")
+	assert.Nil(t, err)
+
+	// every message of the locale is known and valid
+	for name, text := range messages {
+		_, ok := defaultMessages[name]
+		assert.True(t, ok, name)
+		_, err := template.New(name).Funcs(templateFuncs).Parse(text)
+		assert.Nil(t, err, name)
+	}
+
+	conf := testConf()
+	conf.Messages = Messages{Locale: "fr", Dir: "../../messages"}
+	_, fake := newTestGambling(t, conf)
+
+	fake.send("chan", "admin", "!gamble create val pl")
+	fake.send("chan", "alice", "!gamble vote val")
+	fake.send("chan", "bob", "!gamble vote nope")
+	fake.send("chan", "admin", "!gamble close")
+
+	assert.Equal(t, []string{
+		"Un nouveau vote est ouvert ! Votez avec '!gamble vote <vote> (choix : val ou pl)'",
+		"Le vote est fermé, place aux statistiques ! Participants : 1 | val : 1 (100.00%)",
+	}, fake.Said())
+	assert.Equal(t, []sentMessage{
+		{To: "alice", Message: "Pour information, votre vote pour val a bien été pris en compte (envoyé le 2020-04-02)"},
+		{To: "bob", Message: "Désolé mais votre commande de vote n'est pas valide, vous avez peut-être fait une erreur, réessayez (envoyé le 2020-04-02)"},
+	}, fake.Whispered())
+
+	fake.send("chan", "admin", "!gamble roll val")
+	assert.Equal(t, []string{"@admin  : Et... Le gagnant est... alice"}, fake.Said())
+}
@@ -0,0 +1,314 @@
+package app
+
+import (
+	"strconv"
+	"strings"
+
+	twitch "github.com/gempir/go-twitch-irc/v2"
+)
+
+// Vote modes, chosen at creation
+const (
+	// One choice per voter
+	modeSingle = "single"
+	// Up to MaxChoices choices per voter
+	modeMulti = "multi"
+	// Choices ranked by each voter, counted using instant-runoff
+	modeRanked = "ranked"
+	// A number guessed by each voter, optionally within a range
+	modeNumber = "number"
+)
+
+// Round is a structure containing the tallies of an instant-runoff round
+type Round struct {
+	Tallies []Tally `json:"tallies"`
+	// Ballots still counting, exhausted ballots are excluded
+	Active int `json:"active"`
+	// Choices eliminated at the end of the round
+	Eliminated []string `json:"eliminated,omitempty"`
+}
+
+// Runoff is a structure containing the result of a ranked-choice vote
+type Runoff struct {
+	Rounds []Round `json:"rounds"`
+	// Choice with a majority of active ballots, empty if none
+	Winner string `json:"winner,omitempty"`
+	// Choices tied at the last round, when there is no winner
+	Tied []string `json:"tied,omitempty"`
+}
+
+// extractMode is used to extract an optional vote mode from create args,
+// multi can be followed by the maximum number of choices
+func extractMode(args []string) (string, int, []string) {
+	if len(args) < 1 {
+		return modeSingle, 1, args
+	}
+
+	switch strings.ToLower(args[0]) {
+	case modeSingle:
+		return modeSingle, 1, args[1:]
+	case modeRanked:
+		return modeRanked, 0, args[1:]
+	case modeNumber:
+		return modeNumber, 1, args[1:]
+	case modeMulti:
+		if len(args) > 1 {
+			if max, err := strconv.Atoi(args[1]); err == nil && max > 0 {
+				return modeMulti, max, args[2:]
+			}
+		}
+		// no maximum, all choices can be selected
+		return modeMulti, 0, args[1:]
+	}
+
+	return modeSingle, 1, args
+}
+
+// mode returns the mode of a vote, votes stored by older versions are single choice votes
+func (v *Vote) mode() string {
+	if v.Mode == "" {
+		return modeSingle
+	}
+
+	return v.Mode
+}
+
+// ballot returns choices of a user, in order
+func (v *Vote) ballot(user string) []string {
+	if b, ok := v.Ballots[user]; ok {
+		return b
+	}
+
+	if c, ok := v.Votes[user]; ok {
+		return []string{c}
+	}
+
+	return nil
+}
+
+// counted returns choices of a user counted in tallies : all of them for a multi-select vote,
+// the first one otherwise
+func (v *Vote) counted(user string) []string {
+	b := v.ballot(user)
+
+	if v.mode() != modeMulti && len(b) > 1 {
+		return b[:1]
+	}
+
+	return b
+}
+
+// inBallot returns true if choice is one of the choices of a ballot
+func inBallot(ballot []string, choice string) bool {
+	for _, c := range ballot {
+		if c == choice {
+			return true
+		}
+	}
+
+	return false
+}
+
+// picked returns true if a user choice counts for choice
+func (v *Vote) picked(user string, choice string) bool {
+	// once resolved, the closest guesses of a numeric vote count for the result
+	if v.mode() == modeNumber && v.Outcome != "" && choice == v.Outcome {
+		return v.isClosest(user)
+	}
+
+	for _, c := range v.counted(user) {
+		if c == choice {
+			return true
+		}
+	}
+
+	return false
+}
+
+// parseBallot is used to check vote args against the current vote mode, false if not valid
+func (g *Gambling) parseBallot(args []string) ([]string, bool) {
+	if len(args) < 1 {
+		return nil, false
+	}
+
+	// single choice and numeric votes ignore extra args
+	if g.CurrentVote.mode() == modeSingle || g.CurrentVote.mode() == modeNumber {
+		args = args[:1]
+	}
+
+	var choices []string
+	for _, a := range args {
+		choices = append(choices, g.CurrentVote.normalize(a))
+	}
+	choices = filterPossibilities(choices)
+
+	for _, c := range choices {
+		if !g.isVoteValid(c) {
+			return nil, false
+		}
+	}
+
+	if g.CurrentVote.mode() == modeMulti && g.CurrentVote.MaxChoices > 0 && len(choices) > g.CurrentVote.MaxChoices {
+		return nil, false
+	}
+
+	return choices, true
+}
+
+// castVote is used to store choices of a user, see counted for the ones used by rolls and resolution
+func (g *Gambling) castVote(user twitch.User, choices []string) {
+	id := userID(user)
+
+	g.CurrentVote.Votes[id] = choices[0]
+
+	if g.CurrentVote.mode() != modeSingle {
+		g.CurrentVote.Ballots[id] = choices
+	}
+
+	g.CurrentVote.remember(user, g.Config.Weights.of(user))
+}
+
+// describeBallot returns choices of a ballot as a string, using the current vote mode
+func (g *Gambling) describeBallot(choices []string) string {
+	if g.CurrentVote.mode() == modeRanked {
+		return strings.Join(choices, " > ")
+	}
+
+	return strings.Join(choices, ", ")
+}
+
+// voteUsage returns how to vote, using the current vote mode
+func (g *Gambling) voteUsage() string {
+	switch g.CurrentVote.mode() {
+	case modeMulti:
+		max := g.CurrentVote.MaxChoices
+		if max <= 0 {
+			max = len(g.CurrentVote.Possibilities)
+		}
+		return g.text("usage.multi", vars{"Max": max})
+	case modeRanked:
+		return g.text("usage.ranked", nil)
+	case modeNumber:
+		return g.text("usage.number", nil)
+	}
+
+	return g.text("usage.single", nil)
+}
+
+// runoff is used to count a ranked-choice vote using instant-runoff :
+// while no choice has a majority of active ballots, the choices with the fewest votes are eliminated
+// and their ballots go to the next choice still running
+func runoff(v *Vote) *Runoff {
+	res := &Runoff{Rounds: []Round{}}
+
+	remaining := append([]string{}, v.Possibilities...)
+	eliminated := make(map[string]bool)
+
+	for len(remaining) > 0 {
+		counts := make(map[string]int)
+		round := Round{Tallies: []Tally{}}
+
+		for u := range v.Votes {
+			for _, c := range v.ballot(u) {
+				if !eliminated[c] {
+					counts[c]++
+					round.Active++
+					break
+				}
+			}
+		}
+
+		// follow possibilities order, so rounds are always the same for a given vote
+		min := -1
+		for _, c := range remaining {
+			t := Tally{Choice: c, Votes: counts[c]}
+			if round.Active > 0 {
+				t.Percent = float64(t.Votes) / float64(round.Active) * 100
+			}
+			round.Tallies = append(round.Tallies, t)
+
+			if min < 0 || t.Votes < min {
+				min = t.Votes
+			}
+		}
+
+		// nothing left to count
+		if round.Active == 0 {
+			res.Rounds = append(res.Rounds, round)
+			return res
+		}
+
+		for _, t := range round.Tallies {
+			if t.Votes*2 > round.Active {
+				res.Winner = t.Choice
+				res.Rounds = append(res.Rounds, round)
+				return res
+			}
+		}
+
+		var running []string
+		for _, t := range round.Tallies {
+			if t.Votes == min {
+				round.Eliminated = append(round.Eliminated, t.Choice)
+				continue
+			}
+			running = append(running, t.Choice)
+		}
+
+		res.Rounds = append(res.Rounds, round)
+
+		// all remaining choices are tied, no one can be eliminated
+		if len(running) == 0 {
+			res.Rounds[len(res.Rounds)-1].Eliminated = nil
+			res.Tied = remaining
+			return res
+		}
+
+		for _, c := range round.Eliminated {
+			eliminated[c] = true
+		}
+		remaining = running
+	}
+
+	return res
+}
+
+// closeSummary returns the results announced when a vote is closed
+func closeSummary(c *Catalogue, v *Vote, st Statistics) string {
+	var parts []string
+
+	if v.mode() == modeNumber {
+		return numberSummary(c, st)
+	}
+
+	// ranked-choice votes announce each round
+	if st.Runoff != nil {
+		for i, r := range st.Runoff.Rounds {
+			var tallies []string
+			for _, t := range r.Tallies {
+				tallies = append(tallies, c.tally(t))
+			}
+			parts = append(parts, c.render("stats.round", vars{"Round": i + 1, "Tallies": tallies}))
+		}
+
+		if st.Runoff.Winner != "" {
+			parts = append(parts, c.render("stats.winner", vars{"Winner": st.Runoff.Winner}))
+		}
+		if len(st.Runoff.Tied) > 0 {
+			parts = append(parts, c.render("stats.tie", vars{"Tied": st.Runoff.Tied}))
+		}
+
+		return c.render("stats.ranked", vars{"Total": st.Total, "Parts": parts})
+	}
+
+	// follow possibilities order, so the message is always the same for a given vote
+	for _, k := range v.Possibilities {
+		users, ok := st.Transformed[k]
+		if !ok {
+			continue
+		}
+		parts = append(parts, c.tally(Tally{Choice: k, Votes: len(users), Percent: (float64(len(users)) / float64(st.Total)) * 100}))
+	}
+
+	return c.render("stats", vars{"Total": st.Total, "Tallies": parts, "Weighted": weightedSummary(c, v, st)})
+}
@@ -0,0 +1,155 @@
+package app
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExtractMode(t *testing.T) {
+	mode, max, args := extractMode([]string{"a", "b"})
+	assert.Equal(t, modeSingle, mode)
+	assert.Equal(t, 1, max)
+	assert.Equal(t, []string{"a", "b"}, args)
+
+	mode, max, args = extractMode([]string{"Multi", "3", "a", "b"})
+	assert.Equal(t, modeMulti, mode)
+	assert.Equal(t, 3, max)
+	assert.Equal(t, []string{"a", "b"}, args)
+
+	mode, max, args = extractMode([]string{"multi", "a", "b"})
+	assert.Equal(t, modeMulti, mode)
+	assert.Equal(t, 0, max)
+	assert.Equal(t, []string{"a", "b"}, args)
+
+	mode, _, args = extractMode([]string{"ranked", "a", "b"})
+	assert.Equal(t, modeRanked, mode)
+	assert.Equal(t, []string{"a", "b"}, args)
+}
+
+// rankedVote is used to generate a ranked-choice vote from ballots
+func rankedVote(possibilities []string, ballots map[string][]string) *Vote {
+	v := &Vote{
+		Mode:          modeRanked,
+		Possibilities: possibilities,
+		Votes:         make(map[string]string),
+		Ballots:       ballots,
+	}
+
+	for u, b := range ballots {
+		v.Votes[u] = b[0]
+	}
+
+	return v
+}
+
+func TestRunoff(t *testing.T) {
+	v := rankedVote([]string{"a", "b", "c"}, map[string][]string{
+		"u1": {"a", "b"},
+		"u2": {"a"},
+		"u3": {"b", "a"},
+		"u4": {"b"},
+		"u5": {"c", "a"},
+	})
+
+	r := runoff(v)
+
+	assert.Equal(t, "a", r.Winner)
+	assert.Nil(t, r.Tied)
+	assert.Len(t, r.Rounds, 2)
+
+	assert.Equal(t, 5, r.Rounds[0].Active)
+	assert.Equal(t, []string{"c"}, r.Rounds[0].Eliminated)
+	assert.Equal(t, []Tally{{"a", 2, 40}, {"b", 2, 40}, {"c", 1, 20}}, r.Rounds[0].Tallies)
+
+	assert.Equal(t, []Tally{{"a", 3, 60}, {"b", 2, 40}}, r.Rounds[1].Tallies)
+}
+
+func TestRunoffExhaustedBallots(t *testing.T) {
+	v := rankedVote([]string{"a", "b", "c"}, map[string][]string{
+		"u1": {"a"},
+		"u2": {"a"},
+		"u3": {"b"},
+		"u4": {"b"},
+		"u5": {"c"},
+	})
+
+	// c ballot is exhausted once c is eliminated
+	r := runoff(v)
+
+	assert.Len(t, r.Rounds, 2)
+	assert.Equal(t, 4, r.Rounds[1].Active)
+	assert.Equal(t, "", r.Winner)
+	assert.Equal(t, []string{"a", "b"}, r.Tied)
+	assert.Nil(t, r.Rounds[1].Eliminated)
+}
+
+func TestRunoffNoBallots(t *testing.T) {
+	r := runoff(rankedVote([]string{"a", "b"}, map[string][]string{}))
+
+	assert.Len(t, r.Rounds, 1)
+	assert.Equal(t, "", r.Winner)
+	assert.Nil(t, r.Tied)
+}
+
+func TestMultiSelectVote(t *testing.T) {
+	g, fake := newTestGambling(t, testConf())
+
+	fake.send("chan", "admin", "!gamble create multi 2 a b c")
+	assert.Equal(t, []string{"There is a new vote! You can vote for up to 2 choices with '!gamble vote <vote> <vote> ... (choices are : a or b or c)'"}, fake.Said())
+
+	fake.send("chan", "alice", "!gamble vote A b")
+	fake.send("chan", "bob", "!gamble vote b")
+	fake.send("chan", "carol", "!gamble vote a b c")
+	fake.send("chan", "dave", "!gamble vote a nope")
+
+	valid := "For your information, I correctly handled your vote for %s (sent on 2020-04-02)"
+	invalid := "Sorry but the vote command you send is not valid, you may have made a mistake, please retry (sent on 2020-04-02)"
+	assert.Equal(t, []sentMessage{
+		{To: "alice", Message: fmt.Sprintf(valid, "a, b")},
+		{To: "bob", Message: fmt.Sprintf(valid, "b")},
+		{To: "carol", Message: invalid},
+		{To: "dave", Message: invalid},
+	}, fake.Whispered())
+
+	assert.Equal(t, []string{"a", "b"}, g.CurrentVote.Ballots["id-alice"])
+
+	// each choice is counted, percents are based on participants
+	fake.send("chan", "admin", "!gamble close")
+	assert.Equal(t, []string{"Vote is now closed, time for statistics ! Participants : 2 | a : 1 (50.00%), b : 2 (100.00%)"}, fake.Said())
+
+	// alice can be rolled in both teams
+	fake.send("chan", "admin", "!gamble roll a")
+	assert.Equal(t, []string{"@admin  : And... The winner is... alice"}, fake.Said())
+	assert.Equal(t, []string{"id-alice", "id-bob"}, NewStatistics(g.CurrentVote).Transformed["b"])
+}
+
+func TestRankedVote(t *testing.T) {
+	g, fake := newTestGambling(t, testConf())
+
+	fake.send("chan", "admin", "!gamble create ranked a b c")
+	assert.Equal(t, []string{"There is a new vote! You can rank choices from favorite to least favorite with '!gamble vote <first> <second> ... (choices are : a or b or c)'"}, fake.Said())
+
+	fake.send("chan", "u1", "!gamble vote a b")
+	fake.send("chan", "u2", "!gamble vote a")
+	fake.send("chan", "u3", "!gamble vote b a")
+	fake.send("chan", "u4", "!gamble vote b")
+	fake.send("chan", "u5", "!gamble vote c a")
+
+	assert.Contains(t, fake.Whispered(), sentMessage{To: "u5", Message: "For your information, I correctly handled your vote for c > a (sent on 2020-04-02)"})
+
+	fake.send("chan", "admin", "!gamble close")
+	assert.Equal(t, []string{"Vote is now closed, time for statistics ! Participants : 5 | " +
+		"Round 1 : a : 2 (40.00%), b : 2 (40.00%), c : 1 (20.00%) | " +
+		"Round 2 : a : 3 (60.00%), b : 2 (40.00%) | Winner : a"}, fake.Said())
+
+	stats := createStat(g.CurrentVote)
+	assert.Contains(t, stats, "Mode: ranked (first choices below)\n")
+	assert.Contains(t, stats, "Round 1: a (2), b (2), c (1), eliminated: c\n")
+	assert.Contains(t, stats, "Runoff winner: a\n")
+
+	report := NewReport(g.CurrentVote)
+	assert.Equal(t, modeRanked, report.Mode)
+	assert.Equal(t, "a", report.Runoff.Winner)
+}
@@ -0,0 +1,300 @@
+package app
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/apex/log"
+)
+
+// Maximum number of values listed in the distribution announced in the channel
+const maxDistribution = 10
+
+// NumberSummary is a structure containing statistics about guesses of a numeric vote
+type NumberSummary struct {
+	Min    float64 `json:"min"`
+	Max    float64 `json:"max"`
+	Mean   float64 `json:"mean"`
+	Median float64 `json:"median"`
+	// Number of voters by guess, sorted by guess
+	Distribution []Tally `json:"distribution"`
+}
+
+// Guess is a structure containing the guess of a voter, and its distance to the result
+type Guess struct {
+	// Twitch user ID
+	User     string  `json:"user"`
+	Value    float64 `json:"value"`
+	Distance float64 `json:"distance"`
+}
+
+// parseNumber is used to parse a guess, false if it is not a number
+func parseNumber(s string) (float64, bool) {
+	f, err := strconv.ParseFloat(s, 64)
+	if err != nil || math.IsNaN(f) || math.IsInf(f, 0) {
+		return 0, false
+	}
+
+	return f, true
+}
+
+// formatNumber returns a number as short as possible, rounded to 2 decimals
+func formatNumber(f float64) string {
+	return strconv.FormatFloat(math.Round(f*100)/100, 'f', -1, 64)
+}
+
+// extractRange is used to extract optional bounds of a numeric vote from create args
+func extractRange(args []string) (*float64, *float64, bool) {
+	switch len(args) {
+	case 0:
+		return nil, nil, true
+	case 2:
+		min, ok := parseNumber(args[0])
+		if !ok {
+			return nil, nil, false
+		}
+		max, ok := parseNumber(args[1])
+		if !ok || max <= min {
+			return nil, nil, false
+		}
+		return &min, &max, true
+	}
+
+	return nil, nil, false
+}
+
+// exists returns true if the vote has been created
+func (v *Vote) exists() bool {
+	return len(v.Possibilities) > 0 || v.mode() == modeNumber
+}
+
+// normalize returns a choice as stored in votes : lowercased, or formatted for numeric votes
+func (v *Vote) normalize(choice string) string {
+	if v.mode() == modeNumber {
+		if f, ok := parseNumber(choice); ok {
+			return formatNumber(f)
+		}
+	}
+
+	return strings.ToLower(choice)
+}
+
+// inRange returns true if a guess is within the bounds of a numeric vote
+func (v *Vote) inRange(f float64) bool {
+	if v.Min != nil && f < *v.Min {
+		return false
+	}
+	if v.Max != nil && f > *v.Max {
+		return false
+	}
+
+	return true
+}
+
+// choiceList returns choices listed in stats : possibilities, or guesses of a numeric vote sorted by value
+func (v *Vote) choiceList(st Statistics) []string {
+	if v.mode() != modeNumber {
+		return v.Possibilities
+	}
+
+	res := []string{}
+	if st.Numbers != nil {
+		for _, t := range st.Numbers.Distribution {
+			res = append(res, t.Choice)
+		}
+	}
+
+	return res
+}
+
+// isClosest returns true if a user made one of the closest guesses of a resolved numeric vote
+func (v *Vote) isClosest(user string) bool {
+	for _, c := range v.Closest {
+		if c == user {
+			return true
+		}
+	}
+
+	return false
+}
+
+// describeRange returns what can be guessed in a numeric vote
+func (v *Vote) describeRange() string {
+	if v.Min != nil && v.Max != nil {
+		return fmt.Sprintf("a number between %s and %s", formatNumber(*v.Min), formatNumber(*v.Max))
+	}
+
+	return "a number"
+}
+
+// guesses returns all guesses of a numeric vote, sorted by value then user
+func guesses(v *Vote) []Guess {
+	var res []Guess
+
+	for u, c := range v.Votes {
+		if f, ok := parseNumber(c); ok {
+			res = append(res, Guess{User: u, Value: f})
+		}
+	}
+
+	sort.Slice(res, func(i, j int) bool {
+		if res[i].Value != res[j].Value {
+			return res[i].Value < res[j].Value
+		}
+		return res[i].User < res[j].User
+	})
+
+	return res
+}
+
+// numbers is used to compute statistics of a numeric vote, nil if nobody voted
+func numbers(v *Vote) *NumberSummary {
+	gs := guesses(v)
+	if len(gs) == 0 {
+		return nil
+	}
+
+	s := &NumberSummary{
+		Min:          gs[0].Value,
+		Max:          gs[len(gs)-1].Value,
+		Distribution: []Tally{},
+	}
+
+	var sum float64
+	for _, g := range gs {
+		sum += g.Value
+
+		last := len(s.Distribution) - 1
+		if last >= 0 && s.Distribution[last].Choice == formatNumber(g.Value) {
+			s.Distribution[last].Votes++
+			continue
+		}
+		s.Distribution = append(s.Distribution, Tally{Choice: formatNumber(g.Value), Votes: 1})
+	}
+
+	for i := range s.Distribution {
+		s.Distribution[i].Percent = float64(s.Distribution[i].Votes) / float64(len(gs)) * 100
+	}
+
+	s.Mean = sum / float64(len(gs))
+
+	// guesses are sorted
+	if len(gs)%2 == 1 {
+		s.Median = gs[len(gs)/2].Value
+	} else {
+		s.Median = (gs[len(gs)/2-1].Value + gs[len(gs)/2].Value) / 2
+	}
+
+	return s
+}
+
+// closestGuesses returns the n guesses closest to the result, sorted by distance then user,
+// guesses tied with the last one are included
+func closestGuesses(v *Vote, result float64, n int) []Guess {
+	gs := guesses(v)
+
+	for i := range gs {
+		gs[i].Distance = math.Abs(gs[i].Value - result)
+	}
+
+	sort.SliceStable(gs, func(i, j int) bool {
+		if gs[i].Distance != gs[j].Distance {
+			return gs[i].Distance < gs[j].Distance
+		}
+		return gs[i].User < gs[j].User
+	})
+
+	if n <= 0 || len(gs) <= n {
+		return gs
+	}
+
+	last := n
+	for last < len(gs) && gs[last].Distance == gs[n-1].Distance {
+		last++
+	}
+
+	return gs[:last]
+}
+
+// numberSummary returns the results announced when a numeric vote is closed
+func numberSummary(c *Catalogue, st Statistics) string {
+	if st.Numbers == nil {
+		return c.render("stats.total", vars{"Total": st.Total})
+	}
+
+	s := st.Numbers
+
+	var distribution []string
+	for _, t := range s.Distribution {
+		distribution = append(distribution, c.tally(t))
+	}
+
+	return c.render("stats.number", vars{
+		"Total":        st.Total,
+		"Min":          formatNumber(s.Min),
+		"Max":          formatNumber(s.Max),
+		"Mean":         formatNumber(s.Mean),
+		"Median":       formatNumber(s.Median),
+		"Distribution": c.truncate(distribution, maxDistribution),
+	})
+}
+
+// resolveNumber is used to resolve a numeric vote, the closest guesses are announced and their bets are paid
+func (g *Gambling) resolveNumber(args []string) {
+	var result float64
+	ok := len(args) > 0
+	if ok {
+		result, ok = parseNumber(args[0])
+	}
+	if !ok {
+		g.sayAt(g.text("resolve.number_usage", nil), g.admins())
+		return
+	}
+
+	// number of closest guesses announced
+	n := g.Config.Resolve.Closest
+	if len(args) > 1 {
+		if c, err := strconv.Atoi(args[1]); err == nil && c > 0 {
+			n = c
+		}
+	}
+	if n <= 0 {
+		n = 1
+	}
+
+	outcome := formatNumber(result)
+	closest := closestGuesses(g.CurrentVote, result, n)
+
+	g.CurrentVote.Outcome = outcome
+	g.CurrentVote.Closest = []string{}
+	for _, c := range closest {
+		g.CurrentVote.Closest = append(g.CurrentVote.Closest, c.User)
+	}
+
+	winners, pool := g.payBets(outcome)
+
+	g.saveVote()
+	g.publish("resolve")
+
+	log.WithFields(log.Fields{
+		"outcome": outcome,
+		"closest": g.CurrentVote.Closest,
+		"pool":    pool,
+		"winners": winners,
+	}).Info("Vote resolved")
+
+	var listed []string
+	for _, c := range closest {
+		listed = append(listed, g.text("resolve.guess", vars{"Name": g.CurrentVote.name(c.User), "Value": formatNumber(c.Value)}))
+	}
+
+	g.announce(g.text("resolve.number", vars{
+		"Outcome": outcome,
+		"Closest": g.Messages.truncate(listed, g.Config.Resolve.MaxListed),
+		"Ties":    len(closest) > n,
+		"Bets":    g.betsSummary(winners, pool),
+	}))
+}
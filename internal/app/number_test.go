@@ -0,0 +1,132 @@
+package app
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// numberVote is used to generate a numeric vote from guesses
+func numberVote(guesses map[string]string) *Vote {
+	return &Vote{
+		Mode:    modeNumber,
+		Votes:   guesses,
+		Ballots: make(map[string][]string),
+	}
+}
+
+func TestExtractRange(t *testing.T) {
+	min, max, ok := extractRange([]string{})
+	assert.True(t, ok)
+	assert.Nil(t, min)
+	assert.Nil(t, max)
+
+	min, max, ok = extractRange([]string{"-1.5", "10"})
+	assert.True(t, ok)
+	assert.Equal(t, -1.5, *min)
+	assert.Equal(t, 10.0, *max)
+
+	_, _, ok = extractRange([]string{"10", "1"})
+	assert.False(t, ok)
+
+	_, _, ok = extractRange([]string{"a", "10"})
+	assert.False(t, ok)
+
+	_, _, ok = extractRange([]string{"1"})
+	assert.False(t, ok)
+}
+
+func TestNormalize(t *testing.T) {
+	v := numberVote(nil)
+
+	assert.Equal(t, "7", v.normalize("7.00"))
+	assert.Equal(t, "0.33", v.normalize("0.333"))
+	assert.Equal(t, "nope", v.normalize("NOPE"))
+}
+
+func TestNumbers(t *testing.T) {
+	assert.Nil(t, numbers(numberVote(map[string]string{})))
+
+	s := numbers(numberVote(map[string]string{"a": "1", "b": "3", "c": "3", "d": "9"}))
+	assert.Equal(t, 1.0, s.Min)
+	assert.Equal(t, 9.0, s.Max)
+	assert.Equal(t, 4.0, s.Mean)
+	assert.Equal(t, 3.0, s.Median)
+	assert.Equal(t, []Tally{{"1", 1, 25}, {"3", 2, 50}, {"9", 1, 25}}, s.Distribution)
+
+	s = numbers(numberVote(map[string]string{"a": "1", "b": "2", "c": "6"}))
+	assert.Equal(t, 2.0, s.Median)
+	assert.Equal(t, 3.0, s.Mean)
+}
+
+func TestClosestGuesses(t *testing.T) {
+	v := numberVote(map[string]string{"a": "1", "b": "5", "c": "9", "d": "6"})
+
+	gs := closestGuesses(v, 7, 1)
+	assert.Equal(t, []Guess{{"d", 6, 1}}, gs)
+
+	// b and c are both 2 away from the result
+	gs = closestGuesses(v, 7, 2)
+	assert.Equal(t, []Guess{{"d", 6, 1}, {"b", 5, 2}, {"c", 9, 2}}, gs)
+
+	assert.Len(t, closestGuesses(v, 7, 10), 4)
+}
+
+func TestNumberVote(t *testing.T) {
+	conf := testConf()
+	conf.Wallet.Currency = "points"
+	conf.Wallet.Initial = 100
+	g, fake := newTestGambling(t, conf)
+
+	fake.send("chan", "admin", "!gamble create number 10 0")
+	assert.Equal(t, []string{"You need to pass the range as two numbers, or nothing (example : '!gamble create number 0 10')"}, fake.Said())
+
+	fake.send("chan", "admin", "!gamble create number 0 10")
+	assert.Equal(t, []string{"There is a new vote! You can guess with '!gamble vote <number> (choices are : a number between 0 and 10)'"}, fake.Said())
+
+	fake.send("chan", "alice", "!gamble vote 6")
+	fake.send("chan", "bob", "!gamble vote 8.0")
+	fake.send("chan", "carol", "!gamble vote 2")
+	fake.send("chan", "dave", "!gamble vote 11")
+	fake.send("chan", "erin", "!gamble vote seven")
+	fake.send("chan", "frank", "!gamble bet 8 50")
+
+	invalid := "Sorry but the vote command you send is not valid, you may have made a mistake, please retry (sent on 2020-04-02)"
+	assert.Equal(t, []sentMessage{
+		{To: "alice", Message: "For your information, I correctly handled your vote for 6 (sent on 2020-04-02)"},
+		{To: "bob", Message: "For your information, I correctly handled your vote for 8 (sent on 2020-04-02)"},
+		{To: "carol", Message: "For your information, I correctly handled your vote for 2 (sent on 2020-04-02)"},
+		{To: "dave", Message: invalid},
+		{To: "erin", Message: invalid},
+		{To: "frank", Message: "Your bet of 50 points on 8 is registered, your balance is now 50 points (sent on 2020-04-02)"},
+	}, fake.Whispered())
+
+	fake.send("chan", "admin", "!gamble close")
+	assert.Equal(t, []string{"Vote is now closed, time for statistics ! Participants : 4 | min : 2, max : 8, mean : 6, median : 7 | " +
+		"2 : 1 (25.00%), 6 : 1 (25.00%), 8 : 2 (50.00%)"}, fake.Said())
+
+	fake.send("chan", "admin", "!gamble resolve seven")
+	assert.Equal(t, []string{"@admin  : You must specify the result as a number (example : '!gamble resolve 42')"}, fake.Said())
+
+	// alice, bob and frank are all 1 away from the result
+	fake.send("chan", "admin", "!gamble resolve 7 2")
+	assert.Equal(t, []string{"The result is 7 ! Closest guesses : alice (6), bob (8), frank (8) (ties included) | 1 winning bets share a pool of 50 points"}, fake.Said())
+	assert.Equal(t, []string{"id-alice", "id-bob", "id-frank"}, g.CurrentVote.Closest)
+	assert.Equal(t, int64(100), g.Ledger.Balance("id-frank"))
+
+	// only closest voters can be rolled
+	fake.send("chan", "admin", "!gamble roll 7")
+	assert.Len(t, g.CurrentVote.Winners, 1)
+	assert.Contains(t, g.CurrentVote.Closest, g.CurrentVote.Winners[0])
+
+	stats := createStat(g.CurrentVote)
+	assert.Contains(t, stats, "Mode: number (a number between 0 and 10)\n")
+	assert.Contains(t, stats, "Min: 2, Max: 8, Mean: 6, Median: 7\n")
+	assert.Contains(t, stats, "8 (2): bob, frank\n")
+	assert.Contains(t, stats, "Result: 7 (3 closest): alice, bob, frank\n")
+
+	report := NewReport(g.CurrentVote)
+	assert.Equal(t, modeNumber, report.Mode)
+	assert.Equal(t, 6.0, report.Numbers.Mean)
+	assert.Equal(t, []string{"2", "6", "8"}, []string{report.Choices[0].Choice, report.Choices[1].Choice, report.Choices[2].Choice})
+}
@@ -0,0 +1,171 @@
+package app
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/apex/log"
+)
+
+// Delay between two keep alive comments sent on an event stream
+const keepAlive = 15 * time.Second
+
+// overlayPage is a self-contained page drawing the current vote as a bar chart,
+// meant to be used as a browser source in streaming softwares
+const overlayPage = `<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>gambling-bot</title>
+<style>
+  body { margin: 0; padding: 16px; font-family: sans-serif; color: #fff; background: transparent; text-shadow: 1px 1px 2px #000; }
+  #status { font-size: 18px; margin-bottom: 8px; }
+  .choice { margin: 6px 0; }
+  .label { display: flex; justify-content: space-between; font-size: 16px; }
+  .bar { height: 18px; background: rgba(0, 0, 0, 0.4); border-radius: 4px; overflow: hidden; }
+  .fill { height: 100%; background: #9147ff; transition: width 0.5s; }
+  .outcome .fill { background: #00c853; }
+</style>
+</head>
+<body>
+<div id="status">Waiting for a vote...</div>
+<div id="choices"></div>
+<script>
+  var base = location.pathname.replace(/\/$/, "");
+  var status = document.getElementById("status");
+  var choices = document.getElementById("choices");
+
+  function render(state) {
+    if (state.tallies.length === 0) {
+      status.textContent = "Waiting for a vote...";
+      choices.innerHTML = "";
+      return;
+    }
+
+    status.textContent = (state.open ? "Vote open" : "Vote closed") + " - " + state.total + " participants";
+    choices.innerHTML = "";
+
+    state.tallies.forEach(function (t) {
+      var div = document.createElement("div");
+      div.className = "choice" + (state.outcome === t.choice ? " outcome" : "");
+
+      var label = document.createElement("div");
+      label.className = "label";
+      var name = document.createElement("span");
+      name.textContent = t.choice;
+      var count = document.createElement("span");
+      count.textContent = t.votes + " (" + t.percent.toFixed(1) + "%)";
+      label.appendChild(name);
+      label.appendChild(count);
+
+      var bar = document.createElement("div");
+      bar.className = "bar";
+      var fill = document.createElement("div");
+      fill.className = "fill";
+      fill.style.width = t.percent + "%";
+      bar.appendChild(fill);
+
+      div.appendChild(label);
+      div.appendChild(bar);
+      choices.appendChild(div);
+    });
+  }
+
+  var source = new EventSource(base + "/events");
+  source.onmessage = function (e) {
+    render(JSON.parse(e.data).state);
+  };
+</script>
+</body>
+</html>
+`
+
+// handle a call to the overlay of a channel
+//
+//	GET /overlay/<channel>        overlay page
+//	GET /overlay/<channel>/events vote events, as Server-Sent Events
+func (b *Bot) handleOverlay(w http.ResponseWriter, r *http.Request) {
+
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	parts := strings.Split(strings.Trim(strings.TrimPrefix(r.URL.Path, "/overlay/"), "/"), "/")
+	if len(parts) > 2 || (len(parts) == 2 && parts[1] != "events") {
+		http.NotFound(w, r)
+		return
+	}
+
+	g, ok := b.Sessions[strings.ToLower(parts[0])]
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+
+	if len(parts) == 1 {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		fmt.Fprint(w, overlayPage)
+		return
+	}
+
+	g.streamEvents(w, r)
+}
+
+// streamEvents is used to send vote events as Server-Sent Events, starting with the current state
+func (g *Gambling) streamEvents(w http.ResponseWriter, r *http.Request) {
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming not supported", http.StatusInternalServerError)
+		return
+	}
+
+	sub := g.Events.Subscribe()
+	defer g.Events.Unsubscribe(sub)
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+
+	// always start with the current state
+	var state VoteState
+	g.do(func() {
+		state = g.state()
+	})
+	writeEvent(w, VoteEvent{Type: "state", State: state})
+	flusher.Flush()
+
+	log.WithField("channel", g.Config.Twitch.Channel).Info("Overlay connected")
+
+	ticker := time.NewTicker(keepAlive)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			log.WithField("channel", g.Config.Twitch.Channel).Info("Overlay disconnected")
+			return
+		case <-ticker.C:
+			fmt.Fprint(w, ": keep-alive\n\n")
+			flusher.Flush()
+		case event := <-sub:
+			writeEvent(w, event)
+			flusher.Flush()
+		}
+	}
+}
+
+// writeEvent is used to write an event using Server-Sent Events format
+func writeEvent(w http.ResponseWriter, event VoteEvent) {
+	data, err := json.Marshal(event)
+	if err != nil {
+		log.WithError(err).Error("Error encoding vote event")
+		return
+	}
+
+	fmt.Fprintf(w, "event: message\ndata: %s\n\n", data)
+}
@@ -0,0 +1,120 @@
+package app
+
+import (
+	"bufio"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// readEvent is used to read the next vote event of a Server-Sent Events stream
+func readEvent(t *testing.T, r *bufio.Reader) VoteEvent {
+	var event VoteEvent
+
+	for {
+		line, err := r.ReadString('\n')
+		assert.Nil(t, err)
+		if strings.HasPrefix(line, "data: ") {
+			assert.Nil(t, json.Unmarshal([]byte(strings.TrimPrefix(line, "data: ")), &event))
+			return event
+		}
+	}
+}
+
+func TestHub(t *testing.T) {
+	h := NewHub()
+	assert.False(t, h.Subscribed())
+
+	sub := h.Subscribe()
+	assert.True(t, h.Subscribed())
+	h.Publish(VoteEvent{Type: "create"})
+	assert.Equal(t, "create", (<-sub).Type)
+
+	// a slow subscriber never blocks publishing
+	for i := 0; i < subscriberBufferSize+1; i++ {
+		h.Publish(VoteEvent{Type: "vote"})
+	}
+	assert.Len(t, sub, subscriberBufferSize)
+
+	h.Unsubscribe(sub)
+	assert.Empty(t, h.subscribers)
+	assert.False(t, h.Subscribed())
+}
+
+func TestOverlayPage(t *testing.T) {
+	b, _ := newTestBot(t, testConf())
+	server := httptest.NewServer(b.apiHandler())
+	defer server.Close()
+
+	res, err := http.Get(server.URL + "/overlay/chan")
+	assert.Nil(t, err)
+	body, _ := ioutil.ReadAll(res.Body)
+	res.Body.Close()
+	assert.Equal(t, http.StatusOK, res.StatusCode)
+	assert.Contains(t, string(body), "EventSource")
+
+	res, err = http.Get(server.URL + "/overlay/unknown")
+	assert.Nil(t, err)
+	res.Body.Close()
+	assert.Equal(t, http.StatusNotFound, res.StatusCode)
+
+	res, err = http.Get(server.URL + "/overlay/chan/nope")
+	assert.Nil(t, err)
+	res.Body.Close()
+	assert.Equal(t, http.StatusNotFound, res.StatusCode)
+}
+
+func TestOverlayEvents(t *testing.T) {
+	b, fake := newTestBot(t, testConf())
+	server := httptest.NewServer(b.apiHandler())
+	defer server.Close()
+
+	res, err := http.Get(server.URL + "/overlay/chan/events")
+	assert.Nil(t, err)
+	defer res.Body.Close()
+	assert.Equal(t, "text/event-stream", res.Header.Get("Content-Type"))
+
+	r := bufio.NewReader(res.Body)
+
+	// current state first
+	event := readEvent(t, r)
+	assert.Equal(t, "state", event.Type)
+	assert.False(t, event.State.Open)
+
+	fake.send("chan", "admin", "!gamble create val pl")
+	event = readEvent(t, r)
+	assert.Equal(t, "create", event.Type)
+	assert.True(t, event.State.Open)
+	assert.Equal(t, []Tally{{Choice: "val"}, {Choice: "pl"}}, event.State.Tallies)
+
+	fake.send("chan", "admin", "!gamble reset")
+	assert.Equal(t, "reset", readEvent(t, r).Type)
+
+	fake.send("chan", "alice", "!gamble vote val")
+	fake.send("chan", "bob", "!gamble vote pl")
+	fake.send("chan", "carol", "!gamble vote pl")
+	fake.send("chan", "dave", "!gamble vote pl")
+	readEvent(t, r)
+	readEvent(t, r)
+	readEvent(t, r)
+	event = readEvent(t, r)
+	assert.Equal(t, "vote", event.Type)
+	assert.Equal(t, 4, event.State.Total)
+	assert.Equal(t, []Tally{{Choice: "val", Votes: 1, Percent: 25}, {Choice: "pl", Votes: 3, Percent: 75}}, event.State.Tallies)
+
+	fake.send("chan", "admin", "!gamble close")
+	assert.Equal(t, "close", readEvent(t, r).Type)
+
+	fake.send("chan", "admin", "!gamble roll val")
+	event = readEvent(t, r)
+	assert.Equal(t, "roll", event.Type)
+	assert.Equal(t, []string{"alice"}, event.State.Winners)
+
+	fake.send("chan", "admin", "!gamble delete")
+	assert.Equal(t, "delete", readEvent(t, r).Type)
+}
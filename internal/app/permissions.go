@@ -1,6 +1,12 @@
 package app
 
-import "github.com/apex/log"
+import (
+	"github.com/apex/log"
+	twitch "github.com/gempir/go-twitch-irc/v2"
+)
+
+// everyone is a pseudo role matching all users
+const everyone = "everyone"
 
 // Check permission, is the used an admin ?
 func checkPermission(user string, admins []string) bool {
@@ -16,8 +22,103 @@ func checkPermission(user string, admins []string) bool {
 
 	// if user is not admin
 	// not ok
-	// log
-	log.WithField("user", user).Warn("User is not listed as admin")
-	// then return
+	return false
+}
+
+// Check identity, is the user listed by login or by Twitch user ID ?
+// IDs never change, so they survive a rename
+func checkIdentity(user twitch.User, names []string, ids []string) bool {
+	if checkPermission(user.Name, names) {
+		return true
+	}
+
+	return user.ID != "" && checkPermission(user.ID, ids)
+}
+
+// seeAdmin is used to remember the login of an admin listed by Twitch user ID, so they can be notified
+func (g *Gambling) seeAdmin(user twitch.User) {
+	if user.ID == "" || !checkPermission(user.ID, g.Config.AdminIDs) {
+		return
+	}
+
+	if g.adminLogins == nil {
+		g.adminLogins = make(map[string]string)
+	}
+	g.adminLogins[user.ID] = user.Name
+}
+
+// admins returns the logins of admins to notify, admins listed by Twitch user ID only once seen in the channel
+func (g *Gambling) admins() []string {
+	admins := append([]string{}, g.Config.Admins...)
+
+	for _, id := range g.Config.AdminIDs {
+		login, ok := g.adminLogins[id]
+		if ok && !checkPermission(login, admins) {
+			admins = append(admins, login)
+		}
+	}
+
+	return admins
+}
+
+// Check roles, does the user have one of the roles (Twitch badges) ?
+func checkRoles(user twitch.User, roles []string) bool {
+
+	for _, r := range roles {
+		if r == everyone {
+			return true
+		}
+
+		if _, ok := user.Badges[r]; ok {
+			return true
+		}
+	}
+
+	return false
+}
+
+// allowed is used to check if a user can run a command
+//   - users in the deny list can not run any command
+//   - admins can run all commands
+//   - commands listed in the permission matrix are allowed to users with one of the listed roles
+//   - other admin commands are allowed to users with one of the default roles
+//   - other commands are allowed to everyone
+func (g *Gambling) allowed(user twitch.User, cmd string, admin bool) bool {
+	perms := g.Config.Permissions
+
+	// explicitly denied
+	if checkIdentity(user, perms.Deny, perms.DenyIDs) {
+		log.WithFields(log.Fields{
+			"user":    user.Name,
+			"id":      user.ID,
+			"command": cmd,
+		}).Warn("User is denied")
+		return false
+	}
+
+	// admins can do everything
+	if checkIdentity(user, g.Config.Admins, g.Config.AdminIDs) {
+		return true
+	}
+
+	roles, ok := perms.Commands[cmd]
+	if !ok {
+		// open command
+		if !admin {
+			return true
+		}
+		roles = perms.Roles
+	}
+
+	if checkRoles(user, roles) {
+		return true
+	}
+
+	log.WithFields(log.Fields{
+		"user":    user.Name,
+		"command": cmd,
+		"badges":  user.Badges,
+	}).Warn("User is not allowed to run this command")
+
 	return false
 }
@@ -0,0 +1,79 @@
+package app
+
+import (
+	"testing"
+
+	twitch "github.com/gempir/go-twitch-irc/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCheckPermission(t *testing.T) {
+	admins := []string{"alice", "bob"}
+
+	assert.True(t, checkPermission("alice", admins))
+	assert.False(t, checkPermission("carol", admins))
+	assert.False(t, checkPermission("carol", nil))
+}
+
+func TestAllowed(t *testing.T) {
+	conf := testConf()
+	conf.Permissions = Permissions{
+		Roles: []string{"broadcaster", "moderator"},
+		Deny:  []string{"admin", "troll"},
+		Commands: map[string][]string{
+			"roll": {"broadcaster"},
+			"vote": {"subscriber", "vip"},
+			"bet":  {everyone},
+		},
+	}
+	conf.Admins = []string{"admin", "streamer"}
+	g, _ := newTestGambling(t, conf)
+
+	broadcaster := twitch.User{Name: "owner", Badges: map[string]int{"broadcaster": 1}}
+	moderator := twitch.User{Name: "mod", Badges: map[string]int{"moderator": 1}}
+	subscriber := twitch.User{Name: "sub", Badges: map[string]int{"subscriber": 12}}
+	viewer := twitch.User{Name: "viewer"}
+
+	tests := []struct {
+		user    twitch.User
+		cmd     string
+		admin   bool
+		allowed bool
+	}{
+		// deny list wins, even over admins
+		{twitch.User{Name: "admin"}, "create", true, false},
+		{twitch.User{Name: "troll", Badges: map[string]int{"moderator": 1}}, "balance", false, false},
+		// admins can do everything
+		{twitch.User{Name: "streamer"}, "roll", true, true},
+		{twitch.User{Name: "streamer"}, "vote", false, true},
+		// default roles for admin commands
+		{moderator, "create", true, true},
+		{broadcaster, "close", true, true},
+		{subscriber, "close", true, false},
+		{viewer, "create", true, false},
+		// permission matrix
+		{broadcaster, "roll", true, true},
+		{moderator, "roll", true, false},
+		{subscriber, "vote", false, true},
+		{viewer, "vote", false, false},
+		{viewer, "bet", false, true},
+		// other commands are open
+		{viewer, "balance", false, true},
+	}
+
+	for _, test := range tests {
+		assert.Equal(t, test.allowed, g.allowed(test.user, test.cmd, test.admin), "%s running %s", test.user.Name, test.cmd)
+	}
+}
+
+func TestModeratorCreatesVote(t *testing.T) {
+	conf := testConf()
+	conf.Permissions.Roles = []string{"moderator"}
+	g, fake := newTestGambling(t, conf)
+
+	fake.sendAs("chan", twitch.User{Name: "mod", Badges: map[string]int{"moderator": 1}}, "!gamble create val pl")
+	assert.True(t, g.CurrentVote.IsOpen)
+
+	fake.sendAs("chan", twitch.User{Name: "sub", Badges: map[string]int{"subscriber": 1}}, "!gamble close")
+	assert.True(t, g.CurrentVote.IsOpen)
+}
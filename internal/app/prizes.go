@@ -0,0 +1,336 @@
+package app
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/apex/log"
+	twitch "github.com/gempir/go-twitch-irc/v2"
+	"gopkg.in/yaml.v2"
+)
+
+// deliveriesFile is the name of the file used to store delivered prize codes
+const deliveriesFile = "prizes.json"
+
+// noPrize is the argument used to detach the prize of a vote
+const noPrize = "none"
+
+// Delivery is a structure containing a prize code delivered to a winner
+type Delivery struct {
+	Prize string `json:"prize"`
+	Code  string `json:"code"`
+	// Vote identifier
+	Vote string `json:"vote"`
+	// Twitch user ID of the winner
+	Winner string `json:"winner"`
+	// Login of the winner
+	Name string    `json:"name"`
+	At   time.Time `json:"at"`
+}
+
+// Inventory is a structure containing codes of each prize, shared by all channels so a code is never delivered twice
+type Inventory struct {
+	mutex sync.Mutex
+	// File used to persist deliveries, memory only if empty
+	path string
+	// Codes, by prize, in catalogue order
+	Codes map[string][]string
+	// Delivered codes, oldest first
+	Deliveries []Delivery
+}
+
+// NewInventory is used to init an Inventory, loading codes from a catalogue (YAML or CSV) and deliveries stored inside dir
+// if dir is empty, deliveries are kept in memory only
+func NewInventory(catalogue string, dir string) (*Inventory, error) {
+	codes, err := loadCatalogue(catalogue)
+	if err != nil {
+		return nil, fmt.Errorf("Error reading prize catalogue %s : %s", catalogue, err)
+	}
+
+	i := &Inventory{Codes: codes, Deliveries: []Delivery{}}
+
+	if dir == "" {
+		log.Warn("No storage directory configured, delivered prize codes may be delivered again after a restart")
+		return i, nil
+	}
+
+	err = os.MkdirAll(dir, 0755)
+	if err != nil {
+		return nil, err
+	}
+
+	i.path = filepath.Join(dir, deliveriesFile)
+
+	data, err := ioutil.ReadFile(i.path)
+	if os.IsNotExist(err) {
+		return i, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	err = json.Unmarshal(data, &i.Deliveries)
+	if err != nil {
+		return nil, fmt.Errorf("Error reading deliveries file %s : %s", i.path, err)
+	}
+
+	return i, nil
+}
+
+// loadCatalogue is used to read codes by prize, prize names are lowercased
+//   - a CSV file contains one "prize,code" record per line, with an optional header
+//   - a YAML file contains a list of codes by prize
+func loadCatalogue(path string) (map[string][]string, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	codes := make(map[string][]string)
+
+	if strings.EqualFold(filepath.Ext(path), ".csv") {
+		records, err := csv.NewReader(strings.NewReader(string(data))).ReadAll()
+		if err != nil {
+			return nil, err
+		}
+
+		for n, r := range records {
+			if len(r) != 2 {
+				return nil, fmt.Errorf("line %d : expected 2 fields (prize,code), got %d", n+1, len(r))
+			}
+			if n == 0 && strings.EqualFold(r[0], "prize") && strings.EqualFold(r[1], "code") {
+				continue
+			}
+
+			prize := strings.ToLower(strings.TrimSpace(r[0]))
+			codes[prize] = append(codes[prize], strings.TrimSpace(r[1]))
+		}
+
+		return codes, nil
+	}
+
+	var raw map[string][]string
+	err = yaml.Unmarshal(data, &raw)
+	if err != nil {
+		return nil, err
+	}
+
+	for prize, list := range raw {
+		prize = strings.ToLower(prize)
+		codes[prize] = append(codes[prize], list...)
+	}
+
+	return codes, nil
+}
+
+// delivered returns codes already delivered for a prize
+func (i *Inventory) delivered(prize string) map[string]bool {
+	res := make(map[string]bool)
+
+	for _, d := range i.Deliveries {
+		if d.Prize == prize {
+			res[d.Code] = true
+		}
+	}
+
+	return res
+}
+
+// Prizes returns prize names, sorted
+func (i *Inventory) Prizes() []string {
+	var res []string
+	for p := range i.Codes {
+		res = append(res, p)
+	}
+
+	sort.Strings(res)
+
+	return res
+}
+
+// Left returns the number of codes which can still be delivered for a prize
+func (i *Inventory) Left(prize string) int {
+	i.mutex.Lock()
+	defer i.mutex.Unlock()
+
+	delivered := i.delivered(prize)
+
+	n := 0
+	for _, c := range i.Codes[prize] {
+		if !delivered[c] {
+			n++
+		}
+	}
+
+	return n
+}
+
+// Deliver returns the next unused code of a prize, marked as delivered to a winner before being returned
+func (i *Inventory) Deliver(prize string, vote string, winner string, name string) (string, error) {
+	i.mutex.Lock()
+	defer i.mutex.Unlock()
+
+	codes, ok := i.Codes[prize]
+	if !ok {
+		return "", fmt.Errorf("Unknown prize %s", prize)
+	}
+
+	delivered := i.delivered(prize)
+
+	for _, c := range codes {
+		if delivered[c] {
+			continue
+		}
+
+		i.Deliveries = append(i.Deliveries, Delivery{Prize: prize, Code: c, Vote: vote, Winner: winner, Name: name, At: now()})
+
+		// a code which is not persisted could be delivered again after a restart
+		err := i.save()
+		if err != nil {
+			i.Deliveries = i.Deliveries[:len(i.Deliveries)-1]
+			return "", err
+		}
+
+		return c, nil
+	}
+
+	return "", fmt.Errorf("No code left for prize %s", prize)
+}
+
+// save writes deliveries into the deliveries file, if any
+func (i *Inventory) save() error {
+	if i.path == "" {
+		return nil
+	}
+
+	data, err := json.Marshal(i.Deliveries)
+	if err != nil {
+		return err
+	}
+
+	return writeFileAtomic(i.path, data)
+}
+
+// stock returns the number of codes left for each prize
+func (i *Inventory) stock(c *Catalogue) []string {
+	var parts []string
+	for _, p := range i.Prizes() {
+		parts = append(parts, c.render("prize.left", vars{"Prize": p, "Left": i.Left(p)}))
+	}
+
+	return parts
+}
+
+// deliverPrizes is used to whisper a code of the vote prize to each winner, winners who already got one are skipped
+// without verified account, no code is used and admins are asked to deliver the prize manually
+func (g *Gambling) deliverPrizes(winners []string) {
+	prize := g.CurrentVote.Prize
+	if prize == "" || g.Prizes == nil {
+		return
+	}
+
+	// codes can not be whispered, they are kept for admins to deliver the prize manually
+	if !g.Config.Verified {
+		var names []string
+		for _, w := range winners {
+			if g.CurrentVote.Claims[w].Prize == "" {
+				names = append(names, g.CurrentVote.name(w))
+			}
+		}
+
+		if len(names) > 0 {
+			g.sayAt(g.text("prize.manual", vars{"Prize": prize, "Winners": strings.Join(names, ", ")}), g.admins())
+		}
+		return
+	}
+
+	for _, w := range winners {
+		if g.CurrentVote.Claims[w].Prize != "" {
+			continue
+		}
+
+		code, err := g.Prizes.Deliver(prize, g.CurrentVote.ID, w, g.CurrentVote.login(w))
+		if err != nil {
+			log.WithError(err).WithField("user", w).Error("Error delivering prize")
+			g.sayAt(g.text("prize.failed", vars{"Prize": prize, "Winner": g.CurrentVote.name(w), "Error": err.Error()}), g.admins())
+			continue
+		}
+
+		if g.CurrentVote.Claims == nil {
+			g.CurrentVote.Claims = make(map[string]Claim)
+		}
+		c := g.CurrentVote.Claims[w]
+		c.Prize = prize
+		g.CurrentVote.Claims[w] = c
+
+		log.WithFields(log.Fields{
+			"prize": prize,
+			"vote":  g.CurrentVote.ID,
+			"user":  w,
+		}).Info("Prize code delivered")
+
+		g.whisper(g.CurrentVote.login(w), g.text("prize.code", vars{"Prize": prize, "Code": code}))
+	}
+
+	g.saveVote()
+}
+
+// sendPrizes is used to deliver prize codes to new winners, unless they must claim their prize first
+func (g *Gambling) sendPrizes(winners []string) {
+	if g.Config.Rolls.ClaimTimeout > 0 {
+		return
+	}
+
+	g.deliverPrizes(winners)
+}
+
+// handle a prize attached to the current vote, its codes are delivered to next winners
+func (g *Gambling) handlePrize(user twitch.User, args []string) {
+
+	if g.Prizes == nil {
+		g.sayAt(g.text("prize.none", nil), g.admins())
+		return
+	}
+
+	if len(args) < 1 {
+		g.sayAt(g.text("prize.stock", vars{"Stock": g.Prizes.stock(g.Messages), "Prize": g.CurrentVote.Prize}), g.admins())
+		return
+	}
+
+	if !g.CurrentVote.exists() {
+		g.sayAt(g.text("prize.vote", nil), g.admins())
+		return
+	}
+
+	prize := strings.ToLower(args[0])
+
+	if prize == noPrize {
+		g.CurrentVote.Prize = ""
+		g.saveVote()
+		g.sayAt(g.text("prize.detached", nil), g.admins())
+		return
+	}
+
+	if _, ok := g.Prizes.Codes[prize]; !ok {
+		g.sayAt(g.text("prize.unknown", vars{"Prize": args[0], "Prizes": g.Prizes.Prizes()}), g.admins())
+		return
+	}
+
+	g.CurrentVote.Prize = prize
+	g.saveVote()
+
+	log.WithFields(log.Fields{
+		"prize": prize,
+		"vote":  g.CurrentVote.ID,
+	}).Info("Prize attached")
+
+	g.sayAt(g.text("prize.attached", vars{"Prize": prize, "Left": g.Prizes.Left(prize)}), g.admins())
+}
@@ -0,0 +1,179 @@
+package app
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLoadCatalogue(t *testing.T) {
+	dir := t.TempDir()
+
+	yml := filepath.Join(dir, "prizes.yml")
+	assert.Nil(t, ioutil.WriteFile(yml, []byte("Arena:\n  - \"A-1\"\n  - \"A-2\"\nsteam:\n  - \"S-1\"\n"), 0644))
+	codes, err := loadCatalogue(yml)
+	assert.Nil(t, err)
+	assert.Equal(t, map[string][]string{"arena": {"A-1", "A-2"}, "steam": {"S-1"}}, codes)
+
+	csv := filepath.Join(dir, "prizes.csv")
+	assert.Nil(t, ioutil.WriteFile(csv, []byte("prize,code\nArena, A-1\nsteam,S-1\narena,A-2\n"), 0644))
+	codes, err = loadCatalogue(csv)
+	assert.Nil(t, err)
+	assert.Equal(t, map[string][]string{"arena": {"A-1", "A-2"}, "steam": {"S-1"}}, codes)
+
+	assert.Nil(t, ioutil.WriteFile(csv, []byte("arena,A-1,extra\n"), 0644))
+	_, err = loadCatalogue(csv)
+	assert.NotNil(t, err)
+
+	_, err = NewInventory(filepath.Join(dir, "nope.yml"), dir)
+	assert.NotNil(t, err)
+}
+
+func TestInventory(t *testing.T) {
+	dir := t.TempDir()
+	catalogue := filepath.Join(dir, "prizes.csv")
+	assert.Nil(t, ioutil.WriteFile(catalogue, []byte("arena,A-1\narena,A-2\n"), 0644))
+
+	i, err := NewInventory(catalogue, dir)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"arena"}, i.Prizes())
+	assert.Equal(t, 2, i.Left("arena"))
+
+	code, err := i.Deliver("arena", "v1", "id-alice", "alice")
+	assert.Nil(t, err)
+	assert.Equal(t, "A-1", code)
+
+	_, err = i.Deliver("steam", "v1", "id-alice", "alice")
+	assert.EqualError(t, err, "Unknown prize steam")
+
+	// delivered codes are never delivered again, even after a restart
+	i, err = NewInventory(catalogue, dir)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, i.Left("arena"))
+	assert.Equal(t, "id-alice", i.Deliveries[0].Winner)
+
+	code, err = i.Deliver("arena", "v1", "id-bob", "bob")
+	assert.Nil(t, err)
+	assert.Equal(t, "A-2", code)
+
+	_, err = i.Deliver("arena", "v1", "id-carol", "carol")
+	assert.EqualError(t, err, "No code left for prize arena")
+	assert.Len(t, i.Deliveries, 2)
+}
+
+// prizeConf returns a test config with a prize catalogue
+func prizeConf(t *testing.T) Conf {
+	dir := t.TempDir()
+	catalogue := filepath.Join(dir, "prizes.yml")
+	assert.Nil(t, ioutil.WriteFile(catalogue, []byte("arena:\n  - \"A-1\"\n  - \"A-2\"\n"), 0644))
+
+	conf := testConf()
+	conf.Storage.Dir = dir
+	conf.Prizes.File = catalogue
+
+	return conf
+}
+
+func TestPrizeDelivery(t *testing.T) {
+	g, fake := newTestGambling(t, prizeConf(t))
+
+	fake.send("chan", "admin", "!gamble prize arena")
+	assert.Equal(t, []string{"@admin  : There is no vote to attach a prize to"}, fake.Said())
+
+	fake.send("chan", "admin", "!gamble create val pl")
+	fake.send("chan", "alice", "!gamble vote val")
+	fake.send("chan", "bob", "!gamble vote val")
+	fake.send("chan", "carol", "!gamble vote val")
+	fake.send("chan", "admin", "!gamble close")
+	fake.Said()
+	fake.Whispered()
+
+	fake.send("chan", "admin", "!gamble prize nope")
+	fake.send("chan", "admin", "!gamble prize Arena")
+	fake.send("chan", "admin", "!gamble prize")
+	assert.Equal(t, []string{
+		"@admin  : nope is not a prize (prizes are : arena)",
+		"@admin  : Winners of this vote will receive a code of arena by whisper (2 left)",
+		"@admin  : Prizes : arena (2 left) | Prize of this vote : arena",
+	}, fake.Said())
+
+	// each winner gets the next unused code instead of the generic message
+	fake.send("chan", "admin", "!gamble roll val 2")
+	fake.Said()
+	whispered := fake.Whispered()
+	assert.Len(t, whispered, 3)
+	assert.Equal(t, "Congrat's ! You won arena, here is your code : A-1 (sent on 2020-04-02)", whispered[0].Message)
+	assert.Equal(t, "Congrat's ! You won arena, here is your code : A-2 (sent on 2020-04-02)", whispered[1].Message)
+	assert.Equal(t, "admin", whispered[2].To)
+	assert.Equal(t, g.CurrentVote.login(g.CurrentVote.Winners[0]), whispered[0].To)
+	assert.Equal(t, "arena", g.CurrentVote.Claims[g.CurrentVote.Winners[0]].Prize)
+
+	// out of codes, the winner gets the generic message and admins are warned
+	fake.send("chan", "admin", "!gamble roll val")
+	said := fake.Said()
+	assert.Len(t, said, 2)
+	assert.True(t, strings.HasPrefix(said[1], "@admin  : arena could not be delivered to "))
+	assert.Equal(t, "Congrat's ! You're the winner ! Contact the streamer to get your reward ! (sent on 2020-04-02)", fake.Whispered()[1].Message)
+
+	fake.send("chan", "admin", "!gamble prize none")
+	assert.Equal(t, []string{"@admin  : No prize is attached to this vote anymore"}, fake.Said())
+	assert.Equal(t, "", g.CurrentVote.Prize)
+}
+
+func TestPrizeOnClaim(t *testing.T) {
+	conf := prizeConf(t)
+	conf.Rolls.ClaimTimeout = time.Minute
+	g, fake := newTestGambling(t, conf)
+
+	fake.send("chan", "admin", "!gamble create val pl")
+	fake.send("chan", "alice", "!gamble vote val")
+	fake.send("chan", "admin", "!gamble close")
+	fake.send("chan", "admin", "!gamble prize arena")
+	fake.send("chan", "admin", "!gamble roll val")
+	fake.Said()
+	fake.Whispered()
+
+	// the code is only delivered once claimed
+	assert.Equal(t, 2, g.Prizes.Left("arena"))
+
+	fake.send("chan", "alice", "!gamble claim")
+	assert.Equal(t, []string{"@alice  : your prize is claimed, your code was sent by whisper"}, fake.Said())
+	assert.Equal(t, []sentMessage{{To: "alice", Message: "Congrat's ! You won arena, here is your code : A-1 (sent on 2020-04-02)"}}, fake.Whispered())
+	assert.Equal(t, 1, g.Prizes.Left("arena"))
+
+	fake.send("chan", "admin", "!gamble prize")
+	assert.Equal(t, []string{"@admin  : Prizes : arena (1 left) | Prize of this vote : arena"}, fake.Said())
+}
+
+func TestUnverifiedPrize(t *testing.T) {
+	conf := prizeConf(t)
+	conf.Verified = false
+	g, fake := newTestGambling(t, conf)
+
+	fake.send("chan", "admin", "!gamble create val pl")
+	fake.send("chan", "alice", "!gamble vote val")
+	fake.send("chan", "admin", "!gamble close")
+	fake.send("chan", "admin", "!gamble prize arena")
+	fake.Said()
+
+	// codes can not be whispered, admins deliver the prize instead
+	fake.send("chan", "admin", "!gamble roll val")
+	assert.Equal(t, []string{
+		"@admin  : And... The winner is... alice",
+		"@admin  : arena must be delivered manually to alice, prize codes can not be whispered without verified account",
+	}, fake.Said())
+	assert.Nil(t, fake.Whispered())
+	assert.Equal(t, 2, g.Prizes.Left("arena"))
+	assert.Equal(t, "", g.CurrentVote.Claims["id-alice"].Prize)
+}
+
+func TestNoPrizeCatalogue(t *testing.T) {
+	_, fake := newTestGambling(t, testConf())
+
+	fake.send("chan", "admin", "!gamble prize arena")
+	assert.Equal(t, []string{"@admin  : There is no prize catalogue"}, fake.Said())
+}
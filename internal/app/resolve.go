@@ -0,0 +1,88 @@
+package app
+
+import (
+	"strings"
+
+	"github.com/apex/log"
+	twitch "github.com/gempir/go-twitch-irc/v2"
+)
+
+// correctVoters returns users who voted for the outcome, sorted by name
+func correctVoters(vote *Vote, outcome string) []string {
+	var users []string
+
+	for u := range vote.Votes {
+		if vote.picked(u, outcome) {
+			users = append(users, u)
+		}
+	}
+
+	vote.sortByName(users)
+
+	return users
+}
+
+// handle a vote resolution, the outcome is announced with correct voters and bets on it are paid
+func (g *Gambling) handleResolve(user twitch.User, args []string) {
+
+	if !g.CurrentVote.exists() {
+		g.sayAt(g.text("resolve.none", nil), g.admins())
+		return
+	}
+
+	if g.CurrentVote.IsOpen {
+		g.sayAt(g.text("not_closed", nil), g.admins())
+		return
+	}
+
+	if g.CurrentVote.Outcome != "" {
+		g.sayAt(g.text("resolve.resolved", vars{"Outcome": g.CurrentVote.Outcome}), g.admins())
+		return
+	}
+
+	if g.CurrentVote.mode() == modeNumber {
+		g.resolveNumber(args)
+		return
+	}
+
+	if len(args) < 1 || !g.isVoteValid(args[0]) {
+		g.sayAt(g.text("resolve.usage", nil), g.admins())
+		return
+	}
+
+	outcome := strings.ToLower(args[0])
+	g.CurrentVote.Outcome = outcome
+
+	correct := correctVoters(g.CurrentVote, outcome)
+	winners, pool := g.payBets(outcome)
+
+	g.saveVote()
+	g.publish("resolve")
+
+	log.WithFields(log.Fields{
+		"outcome": outcome,
+		"correct": len(correct),
+		"pool":    pool,
+		"winners": winners,
+	}).Info("Vote resolved")
+
+	g.announce(g.text("resolve", vars{
+		"Outcome": outcome,
+		"Count":   len(correct),
+		"Correct": g.Messages.truncate(g.CurrentVote.names(correct), g.Config.Resolve.MaxListed),
+		"Bets":    g.betsSummary(winners, pool),
+	}))
+}
+
+// betsSummary returns how bets were paid, empty if nobody bet
+func (g *Gambling) betsSummary(winners int, pool int64) string {
+	if len(g.CurrentVote.Bets) == 0 {
+		return ""
+	}
+
+	if winners > 0 {
+		return g.text("bets.shared", vars{"Winners": winners, "Pool": pool})
+	}
+
+	return g.text("bets.refunded", nil)
+}
@@ -0,0 +1,53 @@
+package app
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTruncate(t *testing.T) {
+	users := []string{"alice", "bob", "carol"}
+
+	assert.Equal(t, "alice, bob, carol", defaultCatalogue.truncate(users, 0))
+	assert.Equal(t, "alice, bob, carol", defaultCatalogue.truncate(users, 3))
+	assert.Equal(t, "alice, bob (and 1 more)", defaultCatalogue.truncate(users, 2))
+}
+
+func TestResolve(t *testing.T) {
+	conf := testConf()
+	conf.Resolve.MaxListed = 2
+	g, fake := newTestGambling(t, conf)
+
+	fake.send("chan", "admin", "!gamble resolve val")
+	assert.Equal(t, []string{"@admin  : There is no vote to resolve"}, fake.Said())
+
+	fake.send("chan", "admin", "!gamble create val pl")
+	fake.send("chan", "dave", "!gamble vote val")
+	fake.send("chan", "bob", "!gamble vote val")
+	fake.send("chan", "carol", "!gamble vote val")
+	fake.send("chan", "alice", "!gamble vote pl")
+	fake.send("chan", "admin", "!gamble close")
+	fake.Said()
+
+	fake.send("chan", "alice", "!gamble resolve val")
+	assert.Nil(t, fake.Said())
+
+	fake.send("chan", "admin", "!gamble resolve")
+	assert.Equal(t, []string{"@admin  : You must specify a valid result (choices are : val or pl)"}, fake.Said())
+
+	fake.send("chan", "admin", "!gamble resolve val")
+	assert.Equal(t, []string{"The result is val ! 3 viewers guessed right : bob, carol (and 1 more)"}, fake.Said())
+	assert.Equal(t, "val", g.CurrentVote.Outcome)
+
+	// losing options can not be rolled anymore
+	fake.send("chan", "admin", "!gamble roll pl")
+	assert.Equal(t, []string{"@admin  : pl lost, you can only roll a winner among val voters"}, fake.Said())
+
+	fake.send("chan", "admin", "!gamble roll VAL")
+	assert.Len(t, g.CurrentVote.Winners, 1)
+	assert.NotEqual(t, "alice", g.CurrentVote.Winners[0])
+
+	// result is part of statistics
+	assert.Contains(t, createStat(g.CurrentVote), "Result: val (3 correct)\n")
+}
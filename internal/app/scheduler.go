@@ -0,0 +1,313 @@
+package app
+
+import (
+	"sync"
+	"time"
+
+	"github.com/apex/log"
+)
+
+// Clock is an interface used by the scheduler to read time, so it can be faked in tests
+type Clock interface {
+	// Now returns the current time
+	Now() time.Time
+	// At returns a chan receiving the time once t is reached
+	At(t time.Time) <-chan time.Time
+}
+
+// systemClock is the Clock using system time
+type systemClock struct{}
+
+func (systemClock) Now() time.Time {
+	return time.Now()
+}
+
+func (systemClock) At(t time.Time) <-chan time.Time {
+	return time.After(time.Until(t))
+}
+
+// Lane is the priority of an outbound message, lower lanes are sent first
+type Lane int
+
+const (
+	// LaneAnnounce is used by winner announcements and winner whispers
+	LaneAnnounce Lane = iota
+	// LaneNormal is used by everything else sent to the channel
+	LaneNormal
+	// Number of lanes, vote acks come after all of them
+	laneCount
+)
+
+// window counts messages sent in a sliding window
+type window struct {
+	limit  int
+	length time.Duration
+	sent   []time.Time
+}
+
+// next returns when a message can be sent in the window
+func (w *window) next(now time.Time) time.Time {
+	// no limit
+	if w.limit <= 0 {
+		return now
+	}
+
+	// forget messages out of the window
+	for len(w.sent) > 0 && !w.sent[0].Add(w.length).After(now) {
+		w.sent = w.sent[1:]
+	}
+
+	if len(w.sent) < w.limit {
+		return now
+	}
+
+	return w.sent[len(w.sent)-w.limit].Add(w.length)
+}
+
+// take counts a message sent now
+func (w *window) take(now time.Time) {
+	if w.limit > 0 {
+		w.sent = append(w.sent, now)
+	}
+}
+
+// bucket limits messages per second, per 30 seconds and per minute
+type bucket struct {
+	windows []*window
+}
+
+// newBucket is used to init a bucket from config
+func newBucket(conf Bucket) *bucket {
+	return &bucket{
+		windows: []*window{
+			{limit: conf.Second, length: time.Second},
+			{limit: conf.HalfMinute, length: 30 * time.Second},
+			{limit: conf.Minute, length: time.Minute},
+		},
+	}
+}
+
+// next returns when a message can be sent, now if the bucket is not full
+func (b *bucket) next(now time.Time) time.Time {
+	next := now
+
+	for _, w := range b.windows {
+		if n := w.next(now); n.After(next) {
+			next = n
+		}
+	}
+
+	return next
+}
+
+// take counts a message sent now
+func (b *bucket) take(now time.Time) {
+	for _, w := range b.windows {
+		w.take(now)
+	}
+}
+
+// outMessage is a message waiting to be sent, to a channel or as a whisper
+type outMessage struct {
+	to   string
+	text string
+}
+
+// Scheduler is used to send all channel messages and whispers of the bot account,
+// in priority order and without exceeding Twitch rate limits
+// messages are sent right away when limits allow it, queued otherwise
+type Scheduler struct {
+	mutex     sync.Mutex
+	clock     Clock
+	transport ChatTransport
+	// Rate limits, shared by all channels
+	chat    *bucket
+	whisper *bucket
+	// Queued messages, by lane
+	said      [laneCount][]outMessage
+	whispered [laneCount][]outMessage
+	// Vote acks of each channel, sent when nothing else is waiting
+	acks []*AckQueue
+	// Next ack queue to pick from, round robin between channels
+	nextAck int
+	// signaled when messages are queued
+	wake chan struct{}
+}
+
+// NewScheduler is used to init a Scheduler struct and start sending queued messages
+func NewScheduler(limits Limits, transport ChatTransport, clock Clock) *Scheduler {
+	s := &Scheduler{
+		clock:     clock,
+		transport: transport,
+		chat:      newBucket(limits.Chat),
+		whisper:   newBucket(limits.Whisper),
+		wake:      make(chan struct{}, 1),
+	}
+
+	go s.run()
+
+	return s
+}
+
+// AddAckQueue registers the vote acks of a channel
+func (s *Scheduler) AddAckQueue(q *AckQueue) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.acks = append(s.acks, q)
+}
+
+// Say sends a message to a channel
+func (s *Scheduler) Say(channel string, message string, l Lane) {
+	s.mutex.Lock()
+	s.said[l] = append(s.said[l], outMessage{to: channel, text: message})
+	s.mutex.Unlock()
+
+	s.Flush()
+}
+
+// Whisper sends a private message to a user
+func (s *Scheduler) Whisper(user string, message string, l Lane) {
+	s.mutex.Lock()
+	s.whispered[l] = append(s.whispered[l], outMessage{to: user, text: message})
+	s.mutex.Unlock()
+
+	s.Flush()
+}
+
+// Flush sends right away all messages allowed by rate limits, the others are sent later
+func (s *Scheduler) Flush() {
+	s.flush()
+
+	// wake up the sender, so it waits for the right time
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+}
+
+// run sends queued messages once rate limits allow it, as long as the bot
+func (s *Scheduler) run() {
+	for {
+		next, pending := s.flush()
+		if !pending {
+			<-s.wake
+			continue
+		}
+
+		select {
+		case <-s.wake:
+		case <-s.clock.At(next):
+		}
+	}
+}
+
+// flush sends all messages allowed by rate limits,
+// it returns when the next message can be sent, and if there is one
+func (s *Scheduler) flush() (time.Time, bool) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	now := s.clock.Now()
+
+	// messages are sent while the lock is held, so they are never reordered
+	nextSay, sayPending := s.flushLanes(&s.said, s.chat, now, s.transport.Say)
+	nextWhisper, whisperPending := s.flushLanes(&s.whispered, s.whisper, now, s.transport.Whisper)
+
+	// acks only use what is left by other whispers
+	if !whisperPending {
+		nextWhisper, whisperPending = s.flushAcks(now)
+	}
+
+	switch {
+	case sayPending && whisperPending:
+		if nextWhisper.Before(nextSay) {
+			return nextWhisper, true
+		}
+		return nextSay, true
+	case sayPending:
+		return nextSay, true
+	case whisperPending:
+		return nextWhisper, true
+	}
+
+	return now, false
+}
+
+// flushLanes sends queued messages in lanes order, until the bucket is full
+func (s *Scheduler) flushLanes(lanes *[laneCount][]outMessage, b *bucket, now time.Time, send func(string, string)) (time.Time, bool) {
+	for l := Lane(0); l < laneCount; l++ {
+		for len(lanes[l]) > 0 {
+			next := b.next(now)
+			if next.After(now) {
+				return next, true
+			}
+
+			m := lanes[l][0]
+			lanes[l] = lanes[l][1:]
+
+			b.take(now)
+			send(m.to, m.text)
+		}
+	}
+
+	return now, false
+}
+
+// flushAcks sends queued vote acks of all channels, until the whisper bucket is full
+func (s *Scheduler) flushAcks(now time.Time) (time.Time, bool) {
+	for {
+		if !s.hasAcks() {
+			return now, false
+		}
+
+		// wait before picking an ack, so it can still be replaced by a newer one
+		next := s.whisper.next(now)
+		if next.After(now) {
+			return next, true
+		}
+
+		q := s.pickAcks()
+		if q == nil {
+			return now, false
+		}
+
+		ack, ok := q.Pop()
+		if !ok {
+			continue
+		}
+
+		s.whisper.take(now)
+		s.transport.Whisper(ack.Username, ack.message)
+		q.markSent()
+
+		log.WithFields(log.Fields{
+			"message": ack.message,
+			"user":    ack.Username,
+		}).Info("Private message send")
+	}
+}
+
+// hasAcks returns true if a channel has waiting acks
+func (s *Scheduler) hasAcks() bool {
+	for _, q := range s.acks {
+		if q.Len() > 0 {
+			return true
+		}
+	}
+
+	return false
+}
+
+// pickAcks returns the next ack queue with waiting acks, nil if there is none
+func (s *Scheduler) pickAcks() *AckQueue {
+	for i := 0; i < len(s.acks); i++ {
+		q := s.acks[(s.nextAck+i)%len(s.acks)]
+		if q.Len() > 0 {
+			s.nextAck = (s.nextAck + i + 1) % len(s.acks)
+			return q
+		}
+	}
+
+	return nil
+}
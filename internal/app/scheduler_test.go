@@ -0,0 +1,185 @@
+package app
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// newTestScheduler is used to create a scheduler plugged on a fake transport and a fake clock
+func newTestScheduler(limits Limits) (*Scheduler, *fakeTransport, *fakeClock) {
+	fake := new(fakeTransport)
+	clock := newFakeClock(time.Date(2020, 4, 2, 12, 0, 0, 0, time.UTC))
+
+	return NewScheduler(limits, fake, clock), fake, clock
+}
+
+// waitSent waits for the scheduler to send n messages to channels and n whispers, and returns them
+func waitSent(t *testing.T, fake *fakeTransport, said int, whispered int) ([]string, []sentMessage) {
+	var s []string
+	var w []sentMessage
+
+	assert.Eventually(t, func() bool {
+		s = append(s, fake.Said()...)
+		w = append(w, fake.Whispered()...)
+		return len(s) >= said && len(w) >= whispered
+	}, time.Second, time.Millisecond)
+
+	return s, w
+}
+
+func TestWindow(t *testing.T) {
+	date := time.Date(2020, 4, 2, 12, 0, 0, 0, time.UTC)
+	w := window{limit: 2, length: time.Second}
+
+	assert.Equal(t, date, w.next(date))
+	w.take(date)
+	w.take(date.Add(500 * time.Millisecond))
+	assert.Equal(t, date.Add(time.Second), w.next(date.Add(600*time.Millisecond)))
+
+	// the first message left the window
+	assert.Equal(t, date.Add(time.Second), w.next(date.Add(time.Second)))
+
+	// no limit
+	w = window{length: time.Second}
+	w.take(date)
+	assert.Equal(t, date, w.next(date))
+}
+
+func TestSchedulerSendsRightAway(t *testing.T) {
+	s, fake, _ := newTestScheduler(Limits{})
+
+	s.Say("chan", "hello", LaneNormal)
+	s.Whisper("alice", "psst", LaneNormal)
+
+	assert.Equal(t, []string{"hello"}, fake.Said())
+	assert.Equal(t, []sentMessage{{To: "alice", Message: "psst"}}, fake.Whispered())
+}
+
+func TestSchedulerPerSecond(t *testing.T) {
+	s, fake, clock := newTestScheduler(Limits{Chat: Bucket{Second: 2}})
+
+	for _, m := range []string{"a", "b", "c", "d", "e"} {
+		s.Say("chan", m, LaneNormal)
+	}
+	assert.Equal(t, []string{"a", "b"}, fake.Said())
+
+	clock.Advance(time.Second)
+	said, _ := waitSent(t, fake, 2, 0)
+	assert.Equal(t, []string{"c", "d"}, said)
+
+	clock.Advance(time.Second)
+	said, _ = waitSent(t, fake, 1, 0)
+	assert.Equal(t, []string{"e"}, said)
+}
+
+func TestSchedulerPerMinute(t *testing.T) {
+	s, fake, clock := newTestScheduler(Limits{Whisper: Bucket{Second: 10, Minute: 2}})
+
+	for _, u := range []string{"a", "b", "c"} {
+		s.Whisper(u, "psst", LaneNormal)
+	}
+	assert.Len(t, fake.Whispered(), 2)
+
+	// a second is not enough
+	clock.Advance(time.Second)
+	time.Sleep(10 * time.Millisecond)
+	assert.Nil(t, fake.Whispered())
+
+	clock.Advance(time.Minute)
+	_, whispered := waitSent(t, fake, 0, 1)
+	assert.Equal(t, []sentMessage{{To: "c", Message: "psst"}}, whispered)
+}
+
+func TestSchedulerTwitchChatLimit(t *testing.T) {
+	s, fake, clock := newTestScheduler(Limits{Chat: tiers["default"].Chat})
+
+	for i := 0; i < 30; i++ {
+		s.Say("chan", "hello", LaneNormal)
+	}
+
+	// one message per second, until 20 messages were sent in the last 30 seconds
+	var sent []time.Time
+	for sec := 0; sec < 45; sec++ {
+		if sec > 0 {
+			clock.Advance(time.Second)
+		}
+
+		if sec < 20 || (sec >= 30 && sec < 40) {
+			said, _ := waitSent(t, fake, 1, 0)
+			assert.Len(t, said, 1, sec)
+			sent = append(sent, clock.Now())
+			continue
+		}
+
+		time.Sleep(10 * time.Millisecond)
+		assert.Nil(t, fake.Said(), sec)
+	}
+	assert.Len(t, sent, 30)
+
+	// no 30 seconds span holds more than 20 messages
+	for i, start := range sent {
+		n := 0
+		for _, at := range sent[i:] {
+			if at.Before(start.Add(30 * time.Second)) {
+				n++
+			}
+		}
+		assert.LessOrEqual(t, n, 20)
+	}
+}
+
+func TestSchedulerPriority(t *testing.T) {
+	s, fake, clock := newTestScheduler(Limits{Chat: Bucket{Second: 1}, Whisper: Bucket{Second: 1}})
+
+	acks := NewAckQueue(bufferSize)
+	s.AddAckQueue(acks)
+
+	// fill buckets
+	s.Say("chan", "first", LaneNormal)
+	acks.Push(NewVoteAck("ack", "alice"))
+	s.Flush()
+	fake.Said()
+	fake.Whispered()
+
+	s.Say("chan", "normal", LaneNormal)
+	acks.Push(NewVoteAck("ack", "bob"))
+	s.Flush()
+	s.Say("chan", "winner", LaneAnnounce)
+	s.Whisper("carol", "you won", LaneAnnounce)
+
+	// announcements go first
+	clock.Advance(time.Second)
+	said, whispered := waitSent(t, fake, 1, 1)
+	assert.Equal(t, []string{"winner"}, said)
+	assert.Equal(t, []sentMessage{{To: "carol", Message: "you won"}}, whispered)
+
+	clock.Advance(time.Second)
+	said, whispered = waitSent(t, fake, 1, 1)
+	assert.Equal(t, []string{"normal"}, said)
+	assert.Equal(t, []sentMessage{{To: "bob", Message: "ack"}}, whispered)
+}
+
+func TestSchedulerAcksRoundRobin(t *testing.T) {
+	s, fake, clock := newTestScheduler(Limits{Whisper: Bucket{Second: 1}})
+
+	first := NewAckQueue(bufferSize)
+	second := NewAckQueue(bufferSize)
+	s.AddAckQueue(first)
+	s.AddAckQueue(second)
+
+	first.Push(NewVoteAck("ack", "a1"))
+	first.Push(NewVoteAck("ack", "a2"))
+	second.Push(NewVoteAck("ack", "b1"))
+	s.Flush()
+
+	var users []string
+	for i := 0; i < 3; i++ {
+		_, whispered := waitSent(t, fake, 0, 1)
+		users = append(users, whispered[0].To)
+		clock.Advance(time.Second)
+	}
+
+	assert.Equal(t, []string{"a1", "b1", "a2"}, users)
+}
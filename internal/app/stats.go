@@ -1,6 +1,9 @@
 package app
 
 import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
 	"fmt"
 	"os"
 	"strconv"
@@ -12,29 +15,64 @@ import (
 
 // Statistics is a struct containing generated stats for a given vote
 type Statistics struct {
-	Total       int
+	Total int
+	// Voters (user IDs) by choice, a multi-select voter is listed for each choice
+	// and a ranked-choice voter for the first one
 	Transformed map[string][]string
+	// Instant-runoff result, for ranked-choice votes only
+	Runoff *Runoff
+	// Guesses summary, for numeric votes only
+	Numbers *NumberSummary
+	// Weight of voters by choice, and of all participants,
+	// for single choice and multi-select votes with a weighted voter only
+	Weighted    map[string]float64
+	TotalWeight float64
 }
 
 // NewStatistics if used to transform a vote into a statistics struct
 func NewStatistics(votes *Vote) Statistics {
 
 	tr := make(map[string][]string)
-	for u, v := range votes.Votes {
-		tr[v] = append(tr[v], u)
+	for u := range votes.Votes {
+		for _, v := range votes.counted(u) {
+			tr[v] = append(tr[v], u)
+		}
+	}
+
+	// sort voters, so stats are always the same for a given vote
+	for _, users := range tr {
+		votes.sortByName(users)
 	}
 
 	total := len(votes.Votes)
 
 	log.WithFields(log.Fields{
 		"total": total,
-	}).Info("Statistics struct generated")
+	}).Debug("Statistics struct generated")
 
-	return Statistics{
+	st := Statistics{
 		Total:       total,
 		Transformed: tr,
 	}
 
+	if votes.mode() == modeRanked {
+		st.Runoff = runoff(votes)
+	}
+	if votes.mode() == modeNumber {
+		st.Numbers = numbers(votes)
+	}
+	if (votes.mode() == modeSingle || votes.mode() == modeMulti) && votes.weighted() {
+		st.Weighted = make(map[string]float64)
+		for u := range votes.Votes {
+			st.TotalWeight += votes.weight(u)
+			for _, c := range votes.counted(u) {
+				st.Weighted[c] += votes.weight(u)
+			}
+		}
+	}
+
+	return st
+
 }
 
 // Create stats from vote
@@ -43,30 +81,260 @@ func createStat(votes *Vote) string {
 	stats := NewStatistics(votes)
 
 	str := "Total: " + strconv.Itoa(stats.Total) + "\n"
-	for value, users := range stats.Transformed {
-		str += value + " (" + strconv.Itoa(len(users)) + "): " + strings.Join(users, ", ") + "\n"
+
+	// add mode, for other than single choice votes
+	switch votes.mode() {
+	case modeMulti:
+		str += "Mode: multi"
+		if votes.MaxChoices > 0 {
+			str += " (up to " + strconv.Itoa(votes.MaxChoices) + " choices)"
+		}
+		str += "\n"
+	case modeRanked:
+		str += "Mode: ranked (first choices below)\n"
+	case modeNumber:
+		str += "Mode: number (" + votes.describeRange() + ")\n"
+		if n := stats.Numbers; n != nil {
+			str += "Min: " + formatNumber(n.Min) + ", Max: " + formatNumber(n.Max) +
+				", Mean: " + formatNumber(n.Mean) + ", Median: " + formatNumber(n.Median) + "\n"
+		}
+	}
+
+	// follow possibilities order, so the output is always the same for a given vote
+	for _, value := range votes.choiceList(stats) {
+		users, ok := stats.Transformed[value]
+		if !ok {
+			continue
+		}
+		count := strconv.Itoa(len(users))
+		if stats.Weighted != nil {
+			count += ", weight " + formatNumber(stats.Weighted[value])
+		}
+		str += value + " (" + count + "): " + strings.Join(votes.names(users), ", ") + "\n"
+	}
+
+	// add instant-runoff rounds
+	if stats.Runoff != nil {
+		for i, r := range stats.Runoff.Rounds {
+			var tallies []string
+			for _, t := range r.Tallies {
+				tallies = append(tallies, t.Choice+" ("+strconv.Itoa(t.Votes)+")")
+			}
+			str += "Round " + strconv.Itoa(i+1) + ": " + strings.Join(tallies, ", ")
+			if len(r.Eliminated) > 0 {
+				str += ", eliminated: " + strings.Join(r.Eliminated, ", ")
+			}
+			str += "\n"
+		}
+		if stats.Runoff.Winner != "" {
+			str += "Runoff winner: " + stats.Runoff.Winner + "\n"
+		}
+		if len(stats.Runoff.Tied) > 0 {
+			str += "Runoff tie: " + strings.Join(stats.Runoff.Tied, ", ") + "\n"
+		}
+	}
+
+	// add result, once resolved
+	if votes.Outcome != "" && votes.mode() == modeNumber {
+		str += "Result: " + votes.Outcome + " (" + strconv.Itoa(len(votes.Closest)) + " closest): " + strings.Join(votes.names(votes.Closest), ", ") + "\n"
+	} else if votes.Outcome != "" {
+		str += "Result: " + votes.Outcome + " (" + strconv.Itoa(len(stats.Transformed[votes.Outcome])) + " correct)\n"
+	}
+
+	// add winners, once rolled
+	if len(votes.Winners) > 0 {
+		str += "Winners: " + strings.Join(votes.names(votes.Winners), ", ") + "\n"
+	}
+
+	// add open and close dates
+	if !votes.OpenedAt.IsZero() {
+		str += "Opened: " + votes.OpenedAt.Format(time.RFC3339) + "\n"
+	}
+	if !votes.ClosedAt.IsZero() {
+		str += "Closed: " + votes.ClosedAt.Format(time.RFC3339) + "\n"
 	}
 
 	return str
 
 }
 
+// ChoiceReport is a struct containing stats about a choice of a vote
+type ChoiceReport struct {
+	Choice  string  `json:"choice"`
+	Votes   int     `json:"votes"`
+	Percent float64 `json:"percent"`
+	// Names of voters
+	Voters []string `json:"voters"`
+	// Twitch user IDs of voters, in the same order
+	VoterIDs []string `json:"voter_ids"`
+	// Weight of voters, and its percent of the total weight, for weighted votes only
+	Weight          float64 `json:"weight,omitempty"`
+	WeightedPercent float64 `json:"weighted_percent,omitempty"`
+}
+
+// Report is a struct containing all stats about a vote, used for exports
+type Report struct {
+	ID       string    `json:"id"`
+	OpenedAt time.Time `json:"opened_at"`
+	ClosedAt time.Time `json:"closed_at"`
+	Mode     string    `json:"mode"`
+	Total    int       `json:"total"`
+	// Weight of all participants, for weighted votes only
+	TotalWeight float64        `json:"total_weight,omitempty"`
+	Choices     []ChoiceReport `json:"choices"`
+	Runoff      *Runoff        `json:"runoff,omitempty"`
+	Numbers     *NumberSummary `json:"numbers,omitempty"`
+	Winners     []string       `json:"winners"`
+	Outcome     string         `json:"outcome,omitempty"`
+	Closest     []string       `json:"closest,omitempty"`
+}
+
+// NewReport is used to generate a report from a vote
+func NewReport(votes *Vote) Report {
+
+	stats := NewStatistics(votes)
+
+	report := Report{
+		ID:          votes.ID,
+		OpenedAt:    votes.OpenedAt,
+		ClosedAt:    votes.ClosedAt,
+		Mode:        votes.mode(),
+		Total:       stats.Total,
+		TotalWeight: stats.TotalWeight,
+		Choices:     []ChoiceReport{},
+		Runoff:      stats.Runoff,
+		Numbers:     stats.Numbers,
+		Winners:     votes.names(votes.Winners),
+		Outcome:     votes.Outcome,
+		Closest:     votes.names(votes.Closest),
+	}
+
+	if report.Winners == nil {
+		report.Winners = []string{}
+	}
+
+	for _, p := range votes.choiceList(stats) {
+		c := ChoiceReport{Choice: p, Votes: len(stats.Transformed[p]), Voters: votes.names(stats.Transformed[p]), VoterIDs: stats.Transformed[p]}
+		if c.Voters == nil {
+			c.Voters = []string{}
+			c.VoterIDs = []string{}
+		}
+		if stats.Total > 0 {
+			c.Percent = float64(c.Votes) / float64(stats.Total) * 100
+		}
+		if stats.TotalWeight > 0 {
+			c.Weight = stats.Weighted[p]
+			c.WeightedPercent = c.Weight / stats.TotalWeight * 100
+		}
+		report.Choices = append(report.Choices, c)
+	}
+
+	return report
+}
+
+// Create JSON stats from vote
+func createJSONStat(votes *Vote) ([]byte, error) {
+	return json.MarshalIndent(NewReport(votes), "", "  ")
+}
+
+// Create CSV stats from vote, one line per voter
+func createCSVStat(votes *Vote) ([]byte, error) {
+
+	report := NewReport(votes)
+
+	winners := make(map[string]bool)
+	for _, w := range votes.Winners {
+		winners[w] = true
+	}
+
+	// format a date, empty if not set
+	date := func(t time.Time) string {
+		if t.IsZero() {
+			return ""
+		}
+		return t.Format(time.RFC3339)
+	}
+
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+
+	w.Write([]string{"vote", "opened_at", "closed_at", "choice", "voter", "voter_id", "weight", "winner", "correct"})
+
+	// a guess of a numeric vote is correct if it is one of the closest
+	correct := func(choice string, voter string) bool {
+		if votes.mode() == modeNumber {
+			return votes.isClosest(voter)
+		}
+		return report.Outcome != "" && choice == report.Outcome
+	}
+
+	for _, c := range report.Choices {
+		for i, id := range c.VoterIDs {
+			w.Write([]string{
+				report.ID,
+				date(report.OpenedAt),
+				date(report.ClosedAt),
+				c.Choice,
+				c.Voters[i],
+				id,
+				formatNumber(votes.weight(id)),
+				strconv.FormatBool(winners[id]),
+				strconv.FormatBool(correct(c.Choice, id)),
+			})
+		}
+	}
+
+	w.Flush()
+
+	return buf.Bytes(), w.Error()
+}
+
+// Create stats from vote using a format (text, json or csv)
+func createFormattedStat(votes *Vote, format string) ([]byte, error) {
+	switch format {
+	case "text":
+		return []byte(createStat(votes)), nil
+	case "json":
+		return createJSONStat(votes)
+	case "csv":
+		return createCSVStat(votes)
+	}
+
+	return nil, fmt.Errorf("Unknown statistics format %s (formats are : text, json or csv)", format)
+}
+
+// statsFileName returns a unique file name for a vote, using current time and vote id
+func statsFileName(votes *Vote, format string) string {
+	// text format is written as a .txt file
+	ext := format
+	if format == "text" {
+		ext = "txt"
+	}
+
+	name := now().Format("150405")
+	if votes.ID != "" {
+		name += "-" + votes.ID
+	}
+
+	return name + "." + ext
+}
+
 // Write stats into a file inside a base directory
-func statsToFile(stats string, dir string) error {
+func statsToFile(stats []byte, dir string, name string) error {
 
 	// Create a directory using current date
 	// get current date
-	dt := time.Now()
+	dt := now()
 
 	// forge base dir
 	basedir := fmt.Sprintf("%s/%s", dir, dt.Format("2006-01-02"))
 	// create base dir if not exists
 	if _, err := os.Stat(basedir); os.IsNotExist(err) {
-		os.Mkdir(basedir, 0755)
+		os.MkdirAll(basedir, 0755)
 	}
 
 	// Create file
-	f, err := os.Create(fmt.Sprintf("%s/stats", basedir))
+	f, err := os.Create(fmt.Sprintf("%s/%s", basedir, name))
 	if err != nil {
 		return err
 	}
@@ -74,7 +342,7 @@ func statsToFile(stats string, dir string) error {
 	defer f.Close()
 
 	// Write stuff and return err
-	_, err = f.WriteString(stats)
+	_, err = f.Write(stats)
 
 	return err
 
@@ -1,7 +1,11 @@
 package app
 
 import (
+	"encoding/json"
+	"io/ioutil"
+	"path/filepath"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 )
@@ -9,6 +13,7 @@ import (
 // generateVote is used to generate a test vote struct
 func generateVote() *Vote {
 	vote := new(Vote)
+	vote.Possibilities = []string{"levy", "depraz"}
 	vote.Votes = make(map[string]string)
 	vote.Votes["alice"] = "levy"
 	vote.Votes["bob"] = "depraz"
@@ -37,3 +42,103 @@ depraz (1): bob
 
 	assert.Equal(t, expected, createStat(v))
 }
+
+// generateClosedVote is used to generate a test vote struct, closed and rolled
+func generateClosedVote() *Vote {
+	vote := generateVote()
+	vote.ID = "cafe"
+	vote.Votes["carol"] = "levy"
+	vote.Winners = []string{"carol"}
+	vote.OpenedAt = time.Date(2020, 4, 2, 12, 0, 0, 0, time.UTC)
+	vote.ClosedAt = time.Date(2020, 4, 2, 12, 5, 0, 0, time.UTC)
+
+	return vote
+}
+
+func TestCreateStatsClosed(t *testing.T) {
+	v := generateClosedVote()
+
+	expected := `Total: 3
+levy (2): alice, carol
+depraz (1): bob
+Winners: carol
+Opened: 2020-04-02T12:00:00Z
+Closed: 2020-04-02T12:05:00Z
+`
+
+	assert.Equal(t, expected, createStat(v))
+}
+
+func TestCreateJSONStats(t *testing.T) {
+	v := generateClosedVote()
+	v.Outcome = "depraz"
+
+	data, err := createFormattedStat(v, "json")
+	assert.Nil(t, err)
+
+	var report Report
+	assert.Nil(t, json.Unmarshal(data, &report))
+
+	assert.Equal(t, "cafe", report.ID)
+	assert.Equal(t, 3, report.Total)
+	assert.Equal(t, v.OpenedAt, report.OpenedAt)
+	assert.Equal(t, v.ClosedAt, report.ClosedAt)
+	assert.Equal(t, "depraz", report.Outcome)
+	assert.Equal(t, []string{"carol"}, report.Winners)
+	assert.Equal(t, []ChoiceReport{
+		{Choice: "levy", Votes: 2, Percent: float64(2) / 3 * 100, Voters: []string{"alice", "carol"}, VoterIDs: []string{"alice", "carol"}},
+		{Choice: "depraz", Votes: 1, Percent: float64(1) / 3 * 100, Voters: []string{"bob"}, VoterIDs: []string{"bob"}},
+	}, report.Choices)
+}
+
+func TestCreateCSVStats(t *testing.T) {
+	v := generateClosedVote()
+	v.Outcome = "levy"
+
+	data, err := createFormattedStat(v, "csv")
+	assert.Nil(t, err)
+
+	expected := `vote,opened_at,closed_at,choice,voter,voter_id,weight,winner,correct
+cafe,2020-04-02T12:00:00Z,2020-04-02T12:05:00Z,levy,alice,alice,1,false,true
+cafe,2020-04-02T12:00:00Z,2020-04-02T12:05:00Z,levy,carol,carol,1,true,true
+cafe,2020-04-02T12:00:00Z,2020-04-02T12:05:00Z,depraz,bob,bob,1,false,false
+`
+	assert.Equal(t, expected, string(data))
+
+	_, err = createFormattedStat(v, "xml")
+	assert.NotNil(t, err)
+}
+
+func TestStatsFiles(t *testing.T) {
+	conf := testConf()
+	conf.Stats.Dir = t.TempDir()
+	g, fake := newTestGambling(t, conf)
+
+	fake.send("chan", "admin", "!gamble create levy depraz")
+	fake.send("chan", "alice", "!gamble vote levy")
+	fake.send("chan", "admin", "!gamble close")
+	fake.Said()
+
+	fake.send("chan", "admin", "!gamble stats")
+	fake.send("chan", "admin", "!gamble stats JSON")
+	fake.send("chan", "admin", "!gamble stats csv")
+	fake.send("chan", "admin", "!gamble stats xml")
+	assert.Equal(t, []string{
+		"Statistics generated in private mode",
+		"Statistics generated in private mode",
+		"Statistics generated in private mode",
+		"Unknown statistics format xml (formats are : text, json or csv)",
+	}, fake.Said())
+
+	// one file per vote and format
+	files, err := ioutil.ReadDir(filepath.Join(conf.Stats.Dir, "2020-04-02"))
+	assert.Nil(t, err)
+
+	var names []string
+	for _, f := range files {
+		names = append(names, f.Name())
+	}
+
+	id := g.CurrentVote.ID
+	assert.Equal(t, []string{"120000-" + id + ".csv", "120000-" + id + ".json", "120000-" + id + ".txt"}, names)
+}
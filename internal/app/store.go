@@ -0,0 +1,180 @@
+package app
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/apex/log"
+)
+
+// voteFile is the name of the file used to store the current vote
+const voteFile = "vote.json"
+
+// VoteStore is the interface used to persist the current vote between restarts
+type VoteStore interface {
+	// Save stores a snapshot of the vote
+	Save(vote *Vote) error
+	// Load returns the stored vote, or nil if there is none
+	Load() (*Vote, error)
+	// Clear removes the stored vote
+	Clear() error
+}
+
+// FileVoteStore is a VoteStore writing votes as JSON files inside a directory
+type FileVoteStore struct {
+	// Directory containing the vote file
+	Dir string
+}
+
+// NewFileVoteStore is used to init a FileVoteStore, creating the directory if needed
+func NewFileVoteStore(dir string) (*FileVoteStore, error) {
+	err := os.MkdirAll(dir, 0755)
+	if err != nil {
+		return nil, err
+	}
+
+	return &FileVoteStore{Dir: dir}, nil
+}
+
+// path of the vote file
+func (s *FileVoteStore) path() string {
+	return filepath.Join(s.Dir, voteFile)
+}
+
+// Save writes the vote into the vote file
+func (s *FileVoteStore) Save(vote *Vote) error {
+	data, err := json.Marshal(vote)
+	if err != nil {
+		return err
+	}
+
+	return writeFileAtomic(s.path(), data)
+}
+
+// Load reads the vote file, returns nil without error if it does not exist
+func (s *FileVoteStore) Load() (*Vote, error) {
+	data, err := ioutil.ReadFile(s.path())
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	vote := new(Vote)
+	err = json.Unmarshal(data, vote)
+	if err != nil {
+		return nil, fmt.Errorf("Error reading vote file %s : %s", s.path(), err)
+	}
+
+	return vote, nil
+}
+
+// Clear removes the vote file
+func (s *FileVoteStore) Clear() error {
+	err := os.Remove(s.path())
+	if os.IsNotExist(err) {
+		return nil
+	}
+
+	return err
+}
+
+// writeFileAtomic writes data into a temporary file then moves it, so a crash never leaves a truncated file
+func writeFileAtomic(path string, data []byte) error {
+	tmp := path + ".tmp"
+	err := ioutil.WriteFile(tmp, data, 0644)
+	if err != nil {
+		return err
+	}
+
+	return os.Rename(tmp, path)
+}
+
+// saveVote is used to snapshot the current vote, if a store is configured
+// closed votes are also archived, so later changes (roll, resolve) are kept in history
+func (g *Gambling) saveVote() {
+	g.archiveVote()
+
+	if g.Store == nil {
+		return
+	}
+
+	err := g.Store.Save(g.CurrentVote)
+	if err != nil {
+		log.WithError(err).Error("Error saving vote")
+	}
+}
+
+// clearVote is used to remove the stored vote, if a store is configured
+func (g *Gambling) clearVote() {
+	if g.Store == nil {
+		return
+	}
+
+	err := g.Store.Clear()
+	if err != nil {
+		log.WithError(err).Error("Error removing stored vote")
+	}
+}
+
+// restoreVote is used to load the stored vote, if a store is configured
+// returns true if a vote has been restored
+func (g *Gambling) restoreVote() bool {
+	if g.Store == nil {
+		return false
+	}
+
+	vote, err := g.Store.Load()
+	if err != nil {
+		log.WithError(err).Error("Error loading stored vote")
+		return false
+	}
+
+	// nothing stored, or an empty vote
+	if vote == nil || !vote.exists() {
+		return false
+	}
+
+	if vote.Votes == nil {
+		vote.Votes = make(map[string]string)
+	}
+	if vote.Ballots == nil {
+		vote.Ballots = make(map[string][]string)
+	}
+	if vote.Voters == nil {
+		vote.Voters = make(map[string]Voter)
+	}
+	if vote.Bets == nil {
+		vote.Bets = make(map[string]Bet)
+	}
+
+	// votes stored by older versions have no identifier
+	if vote.ID == "" {
+		vote.ID = newVoteID()
+	}
+
+	g.CurrentVote = vote
+
+	log.WithFields(log.Fields{
+		"choices": vote.Possibilities,
+		"open":    vote.IsOpen,
+		"votes":   len(vote.Votes),
+		"winners": vote.Winners,
+	}).Info("Vote restored")
+
+	return true
+}
+
+// announceRestoredVote is used to inform the channel about a vote restored after a restart
+func (g *Gambling) announceRestoredVote() {
+	if g.CurrentVote.IsOpen {
+		g.say(g.text("restored.open", vars{"Total": len(g.CurrentVote.Votes)}))
+		return
+	}
+
+	g.say(g.text("restored.closed", vars{"Total": len(g.CurrentVote.Votes), "Winners": len(g.CurrentVote.Winners)}))
+}
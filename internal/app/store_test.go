@@ -0,0 +1,78 @@
+package app
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFileVoteStore(t *testing.T) {
+	store, err := NewFileVoteStore(t.TempDir())
+	assert.Nil(t, err)
+
+	// nothing stored yet
+	vote, err := store.Load()
+	assert.Nil(t, err)
+	assert.Nil(t, vote)
+
+	v := generateVote()
+	v.IsOpen = true
+	v.Winners = []string{"alice"}
+	assert.Nil(t, store.Save(v))
+
+	vote, err = store.Load()
+	assert.Nil(t, err)
+	assert.Equal(t, v.Possibilities, vote.Possibilities)
+	assert.Equal(t, v.Votes, vote.Votes)
+	assert.Equal(t, v.Winners, vote.Winners)
+	assert.True(t, vote.IsOpen)
+
+	assert.Nil(t, store.Clear())
+	assert.Nil(t, store.Clear())
+
+	vote, err = store.Load()
+	assert.Nil(t, err)
+	assert.Nil(t, vote)
+}
+
+func TestRestoreVote(t *testing.T) {
+	conf := testConf()
+	conf.Storage.Dir = t.TempDir()
+
+	_, fake := newTestGambling(t, conf)
+	fake.send("chan", "admin", "!gamble create val pl")
+	fake.send("chan", "alice", "!gamble vote val")
+
+	// restart while the vote is open
+	g, fake := newTestGambling(t, conf)
+	assert.True(t, g.CurrentVote.IsOpen)
+	assert.Equal(t, map[string]string{"id-alice": "val"}, g.CurrentVote.Votes)
+
+	assert.Nil(t, fake.Connect())
+	assert.Equal(t, []string{
+		"Hello",
+		"I'm back ! The vote is still open with 1 votes, you can vote with '!gamble vote <vote> (choices are : val or pl)'",
+	}, fake.Said())
+
+	// restored vote still accepts votes
+	fake.send("chan", "bob", "!gamble vote pl")
+	fake.send("chan", "admin", "!gamble close")
+	fake.send("chan", "admin", "!gamble roll pl")
+
+	// restart once closed and rolled
+	g, fake = newTestGambling(t, conf)
+	assert.False(t, g.CurrentVote.IsOpen)
+	assert.Equal(t, []string{"id-bob"}, g.CurrentVote.Winners)
+	assert.Nil(t, fake.Connect())
+	assert.Equal(t, []string{
+		"Hello",
+		"I'm back ! The last vote (choices were : val or pl) is closed with 2 votes and 1 winners",
+	}, fake.Said())
+
+	// nothing restored once deleted
+	fake.send("chan", "admin", "!gamble delete")
+	g, fake = newTestGambling(t, conf)
+	assert.Nil(t, g.CurrentVote.Votes)
+	assert.Nil(t, fake.Connect())
+	assert.Equal(t, []string{"Hello"}, fake.Said())
+}
@@ -0,0 +1,179 @@
+package app
+
+import (
+	"time"
+
+	"github.com/apex/log"
+	twitch "github.com/gempir/go-twitch-irc/v2"
+)
+
+// voteTimer is a structure containing timers used to close a vote automatically
+type voteTimer struct {
+	// Timer closing the vote
+	close *clockTimer
+	// Timers sending reminders before closing
+	reminders []*clockTimer
+}
+
+// stop all timers
+func (t *voteTimer) stop() {
+	t.close.stop()
+	for _, r := range t.reminders {
+		r.stop()
+	}
+}
+
+// clockNow returns the time of the scheduler clock, deadlines must use it since it drives timers
+func (g *Gambling) clockNow() time.Time {
+	return g.Scheduler.clock.Now()
+}
+
+// clockTimer is a structure describing an action waiting for a date of the scheduler clock
+type clockTimer struct {
+	// Closed to cancel the action
+	stopped chan struct{}
+	// Closed once the timer goroutine is gone
+	done chan struct{}
+}
+
+// after is used to run an action on the event loop once the scheduler clock reaches a date
+func (g *Gambling) after(at time.Time, action func()) *clockTimer {
+	t := &clockTimer{stopped: make(chan struct{}), done: make(chan struct{})}
+	reached := g.Scheduler.clock.At(at)
+
+	go func() {
+		defer close(t.done)
+
+		select {
+		case <-reached:
+		case <-t.stopped:
+			return
+		}
+
+		// never wait for the loop once stopped, the loop may be the one stopping the timer
+		select {
+		case g.actions <- action:
+		case <-t.stopped:
+		}
+	}()
+
+	return t
+}
+
+// stop cancels the action, it never runs once stop returned, must be called from the event loop
+func (t *clockTimer) stop() {
+	select {
+	case <-t.stopped:
+	default:
+		close(t.stopped)
+	}
+
+	<-t.done
+}
+
+// Extract an optional duration from the first argument
+func extractDuration(args []string) (time.Duration, []string) {
+	if len(args) < 1 {
+		return 0, args
+	}
+
+	d, err := time.ParseDuration(args[0])
+	if err != nil || d <= 0 {
+		return 0, args
+	}
+
+	return d, args[1:]
+}
+
+// startTimer is used to schedule closing and reminders of the current vote, using its deadline
+func (g *Gambling) startTimer() {
+
+	// ensure there is only one timer running
+	g.stopTimer()
+
+	remaining := g.CurrentVote.Deadline.Sub(g.clockNow())
+
+	t := new(voteTimer)
+
+	// close the vote once the deadline is reached
+	t.close = g.after(g.CurrentVote.Deadline, func() {
+		// ensure this timer is still the current one
+		if g.timer != t {
+			return
+		}
+		log.Info("Vote deadline reached")
+		g.closeVote()
+	})
+
+	// remind viewers to vote
+	for _, r := range g.Config.Timer.Reminders {
+		if r <= 0 || r >= remaining {
+			continue
+		}
+
+		left := r
+		t.reminders = append(t.reminders, g.after(g.CurrentVote.Deadline.Add(-left), func() {
+			if g.timer != t {
+				return
+			}
+			g.say(g.text("timer.reminder", vars{"Left": left}))
+		}))
+	}
+
+	g.timer = t
+
+	log.WithFields(log.Fields{
+		"deadline":  g.CurrentVote.Deadline,
+		"remaining": remaining,
+	}).Info("Vote timer started")
+}
+
+// stopTimer is used to cancel closing and reminders of the current vote
+func (g *Gambling) stopTimer() {
+	if g.timer == nil {
+		return
+	}
+
+	g.timer.stop()
+	g.timer = nil
+
+	log.Info("Vote timer stopped")
+}
+
+// handle a vote timer extension
+func (g *Gambling) handleExtend(user twitch.User, args []string) {
+
+	if !g.CurrentVote.IsOpen || g.timer == nil {
+		g.say(g.text("extend.none", nil))
+		return
+	}
+
+	extension, _ := extractDuration(args)
+	if extension <= 0 {
+		g.say(g.text("extend.usage", nil))
+		return
+	}
+
+	g.CurrentVote.Deadline = g.CurrentVote.Deadline.Add(extension)
+	g.startTimer()
+	g.saveVote()
+	g.publish("extend")
+
+	g.say(g.text("extend", vars{"Extension": extension, "Remaining": g.CurrentVote.Deadline.Sub(g.clockNow()).Round(time.Second)}))
+}
+
+// handle a vote timer cancellation, the vote stays open
+func (g *Gambling) handleCancel(user twitch.User, args []string) {
+
+	if !g.CurrentVote.IsOpen || g.timer == nil {
+		g.say(g.text("cancel.none", nil))
+		return
+	}
+
+	g.stopTimer()
+	g.CurrentVote.Deadline = time.Time{}
+	g.saveVote()
+	g.publish("cancel")
+
+	g.say(g.text("cancel", nil))
+}
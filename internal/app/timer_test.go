@@ -0,0 +1,157 @@
+package app
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// waitSaid is used to wait for n messages sent to channels
+func waitSaid(t *testing.T, fake *fakeTransport, n int) []string {
+	var said []string
+
+	for i := 0; i < 100 && len(said) < n; i++ {
+		time.Sleep(10 * time.Millisecond)
+		said = append(said, fake.Said()...)
+	}
+
+	return said
+}
+
+func TestExtractDuration(t *testing.T) {
+	d, args := extractDuration([]string{"2m", "val", "pl"})
+	assert.Equal(t, 2*time.Minute, d)
+	assert.Equal(t, []string{"val", "pl"}, args)
+
+	d, args = extractDuration([]string{"val", "pl"})
+	assert.Equal(t, time.Duration(0), d)
+	assert.Equal(t, []string{"val", "pl"}, args)
+
+	d, args = extractDuration([]string{"-2m", "pl"})
+	assert.Equal(t, time.Duration(0), d)
+	assert.Equal(t, []string{"-2m", "pl"}, args)
+
+	d, args = extractDuration(nil)
+	assert.Equal(t, time.Duration(0), d)
+	assert.Nil(t, args)
+}
+
+func TestTimedVote(t *testing.T) {
+	conf := testConf()
+	conf.Timer.Reminders = []time.Duration{time.Minute, time.Hour}
+	g, fake := newTestGambling(t, conf)
+
+	fake.send("chan", "admin", "!gamble create 3m val pl")
+	assert.Equal(t, []string{"There is a new vote! You can vote with '!gamble vote <vote> (choices are : val or pl)', it will close automatically in 3m0s"}, fake.Said())
+	assert.Equal(t, []string{"val", "pl"}, g.CurrentVote.Possibilities)
+
+	fake.send("chan", "alice", "!gamble vote pl")
+
+	clockOf(g).Advance(2 * time.Minute)
+	assert.Equal(t, []string{"Hurry up ! Only 1m0s left to vote with '!gamble vote <vote>' (choices are : val or pl)"}, waitSaid(t, fake, 1))
+
+	clockOf(g).Advance(time.Minute)
+	assert.Equal(t, []string{"Vote is now closed, time for statistics ! Participants : 1 | pl : 1 (100.00%)"}, waitSaid(t, fake, 1))
+	assert.False(t, g.CurrentVote.IsOpen)
+	assert.Nil(t, g.timer)
+
+	// nothing left to extend or cancel
+	fake.send("chan", "admin", "!gamble extend 1m")
+	fake.send("chan", "admin", "!gamble cancel")
+	assert.Equal(t, []string{"There is no timed vote to extend", "There is no timer to cancel"}, fake.Said())
+}
+
+func TestExtendTimedVote(t *testing.T) {
+	conf := testConf()
+	conf.Timer.Reminders = []time.Duration{}
+	g, fake := newTestGambling(t, conf)
+
+	fake.send("chan", "admin", "!gamble create 2m val pl")
+	fake.Said()
+
+	fake.send("chan", "alice", "!gamble extend 1m")
+	fake.send("chan", "admin", "!gamble extend")
+	fake.send("chan", "admin", "!gamble extend 1m")
+	assert.Equal(t, []string{
+		"You need to pass a valid duration as argument (example : '!gamble extend 1m')",
+		"Vote extended by 1m0s, it will close automatically in 3m0s",
+	}, fake.Said())
+
+	// the first deadline is gone
+	clockOf(g).Advance(2 * time.Minute)
+	assert.True(t, g.CurrentVote.IsOpen)
+	assert.Nil(t, fake.Said())
+
+	// the remaining time follows the clock driving timers
+	fake.send("chan", "admin", "!gamble extend 1m")
+	assert.Equal(t, []string{"Vote extended by 1m0s, it will close automatically in 2m0s"}, fake.Said())
+
+	fake.send("chan", "admin", "!gamble close")
+	assert.Equal(t, []string{"Vote is now closed, time for statistics ! Participants : 0 | "}, fake.Said())
+	assert.Nil(t, g.timer)
+}
+
+func TestCancelTimedVote(t *testing.T) {
+	conf := testConf()
+	conf.Timer.Reminders = []time.Duration{}
+	g, fake := newTestGambling(t, conf)
+
+	fake.send("chan", "admin", "!gamble create 1m val pl")
+	fake.send("chan", "admin", "!gamble cancel")
+	assert.Equal(t, []string{
+		"There is a new vote! You can vote with '!gamble vote <vote> (choices are : val or pl)', it will close automatically in 1m0s",
+		"Timer cancelled, the vote will stay open until closed with '!gamble close'",
+	}, fake.Said())
+
+	clockOf(g).Advance(2 * time.Minute)
+	assert.True(t, g.CurrentVote.IsOpen)
+	assert.True(t, g.CurrentVote.Deadline.IsZero())
+	assert.Nil(t, fake.Said())
+}
+
+func TestDeleteAndResetStopTimer(t *testing.T) {
+	conf := testConf()
+	conf.Timer.Reminders = []time.Duration{}
+	g, fake := newTestGambling(t, conf)
+
+	fake.send("chan", "admin", "!gamble create 1m val pl")
+	fake.send("chan", "admin", "!gamble reset")
+	assert.Nil(t, g.timer)
+	assert.True(t, g.CurrentVote.Deadline.IsZero())
+
+	fake.send("chan", "admin", "!gamble delete")
+	fake.send("chan", "admin", "!gamble create 1m val pl")
+	fake.send("chan", "admin", "!gamble delete")
+	assert.Nil(t, g.timer)
+	fake.Said()
+
+	clockOf(g).Advance(2 * time.Minute)
+	assert.Nil(t, fake.Said())
+}
+
+func TestRestoreTimedVote(t *testing.T) {
+	conf := testConf()
+	conf.Storage.Dir = t.TempDir()
+	conf.Timer.Reminders = []time.Duration{}
+
+	_, fake := newTestGambling(t, conf)
+	fake.send("chan", "admin", "!gamble create 1m val pl")
+	fake.send("chan", "admin", "!gamble cancel")
+
+	// no timer once cancelled
+	g, _ := newTestGambling(t, conf)
+	assert.True(t, g.CurrentVote.Deadline.IsZero())
+
+	_, fake = newTestGambling(t, conf)
+	fake.send("chan", "admin", "!gamble delete")
+	fake.send("chan", "admin", "!gamble create 1h val pl")
+	fake.Said()
+
+	// timer is resumed on connect
+	g, fake = newTestGambling(t, conf)
+	assert.Nil(t, g.timer)
+	assert.Nil(t, fake.Connect())
+	assert.NotNil(t, g.timer)
+	g.stopTimer()
+}
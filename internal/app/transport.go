@@ -0,0 +1,30 @@
+package app
+
+import (
+	twitch "github.com/gempir/go-twitch-irc/v2"
+)
+
+// ChatTransport is the interface used by a Gambling instance to talk to a chat,
+// the go-twitch-irc client is the default implementation
+type ChatTransport interface {
+	// Say sends a message to a channel
+	Say(channel string, message string)
+	// Whisper sends a private message to a user
+	Whisper(user string, message string)
+	// Join joins one or more channels
+	Join(channels ...string)
+	// Connect connects to the chat and blocks until the connection is closed
+	Connect() error
+	// OnPrivateMessage registers a callback triggered on each channel message
+	OnPrivateMessage(callback func(message twitch.PrivateMessage))
+	// OnConnect registers a callback triggered once connected
+	OnConnect(callback func())
+}
+
+// Ensure the Twitch client implements ChatTransport
+var _ ChatTransport = (*twitch.Client)(nil)
+
+// NewTwitchTransport is used to create a ChatTransport backed by Twitch IRC
+func NewTwitchTransport(username string, oauth string) ChatTransport {
+	return twitch.NewClient(username, oauth)
+}
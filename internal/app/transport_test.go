@@ -0,0 +1,111 @@
+package app
+
+import (
+	"sync"
+
+	twitch "github.com/gempir/go-twitch-irc/v2"
+)
+
+// sentMessage is a message recorded by the fake transport
+type sentMessage struct {
+	To      string
+	Message string
+}
+
+// fakeTransport is an in memory ChatTransport recording everything sent
+type fakeTransport struct {
+	mutex     sync.Mutex
+	said      []sentMessage
+	whispered []sentMessage
+	joined    []string
+	onMessage func(message twitch.PrivateMessage)
+	onConnect func()
+}
+
+// Ensure the fake implements ChatTransport
+var _ ChatTransport = (*fakeTransport)(nil)
+
+func (f *fakeTransport) Say(channel string, message string) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	f.said = append(f.said, sentMessage{To: channel, Message: message})
+}
+
+func (f *fakeTransport) Whisper(user string, message string) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	f.whispered = append(f.whispered, sentMessage{To: user, Message: message})
+}
+
+func (f *fakeTransport) Join(channels ...string) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	f.joined = append(f.joined, channels...)
+}
+
+func (f *fakeTransport) Connect() error {
+	if f.onConnect != nil {
+		f.onConnect()
+	}
+	return nil
+}
+
+func (f *fakeTransport) OnPrivateMessage(callback func(message twitch.PrivateMessage)) {
+	f.onMessage = callback
+}
+
+func (f *fakeTransport) OnConnect(callback func()) {
+	f.onConnect = callback
+}
+
+// send simulates a message sent by a user in a channel
+func (f *fakeTransport) send(channel string, user string, message string) {
+	f.sendAs(channel, twitch.User{ID: "id-" + user, Name: user, DisplayName: user}, message)
+}
+
+// sendAs simulates a message sent by a user, with badges, in a channel
+func (f *fakeTransport) sendAs(channel string, user twitch.User, message string) {
+	f.onMessage(twitch.PrivateMessage{
+		Channel: channel,
+		User:    user,
+		Message: message,
+	})
+}
+
+// Said returns all messages sent to channels and forget them
+func (f *fakeTransport) Said() []string {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	var res []string
+	for _, m := range f.said {
+		res = append(res, m.Message)
+	}
+	f.said = nil
+	return res
+}
+
+// SaidIn returns all messages sent to a channel and forget them
+func (f *fakeTransport) SaidIn(channel string) []string {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	var res []string
+	var others []sentMessage
+	for _, m := range f.said {
+		if m.To == channel {
+			res = append(res, m.Message)
+		} else {
+			others = append(others, m)
+		}
+	}
+	f.said = others
+	return res
+}
+
+// Whispered returns all whispers sent and forget them
+func (f *fakeTransport) Whispered() []sentMessage {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	res := f.whispered
+	f.whispered = nil
+	return res
+}
@@ -0,0 +1,214 @@
+package app
+
+import (
+	"errors"
+	"strconv"
+
+	"github.com/apex/log"
+	twitch "github.com/gempir/go-twitch-irc/v2"
+)
+
+// Bet is a structure containing an amount of virtual currency wagered on a choice
+type Bet struct {
+	Choice string
+	Amount int64
+}
+
+// payouts computes pari-mutuel gains of each bet : the whole pool is shared between
+// winning bets, proportionally to their amount. If nobody bet on the outcome, bets are refunded
+// points left by rounding go to the largest winning bet, so the whole pool is always paid
+func payouts(bets map[string]Bet, outcome string) map[string]int64 {
+	return sharePool(bets, func(user string, b Bet) bool {
+		return b.Choice == outcome
+	})
+}
+
+// sharePool computes pari-mutuel gains of each bet, using a function telling which bets won
+func sharePool(bets map[string]Bet, won func(user string, b Bet) bool) map[string]int64 {
+	var pool, winning int64
+
+	for u, b := range bets {
+		pool += b.Amount
+		if won(u, b) {
+			winning += b.Amount
+		}
+	}
+
+	gains := make(map[string]int64)
+
+	// the largest winning bet gets what is left by rounding down, ties are broken by user
+	var paid int64
+	largest := ""
+
+	for user, b := range bets {
+		// nobody wins, refund
+		if winning == 0 {
+			gains[user] = b.Amount
+			continue
+		}
+
+		if won(user, b) {
+			gains[user] = b.Amount * pool / winning
+			paid += gains[user]
+
+			if largest == "" || b.Amount > bets[largest].Amount || (b.Amount == bets[largest].Amount && user < largest) {
+				largest = user
+			}
+		}
+	}
+
+	if largest != "" {
+		gains[largest] += pool - paid
+	}
+
+	return gains
+}
+
+// refundBets is used to give back all bets of an unresolved vote
+func (g *Gambling) refundBets() {
+
+	// resolved bets are already paid
+	if g.CurrentVote.Outcome != "" {
+		return
+	}
+
+	for user, b := range g.CurrentVote.Bets {
+		_, err := g.Ledger.Add(user, b.Amount)
+		if err != nil {
+			log.WithError(err).WithField("user", user).Error("Error refunding bet")
+		}
+	}
+
+	if len(g.CurrentVote.Bets) > 0 {
+		log.WithField("bets", len(g.CurrentVote.Bets)).Info("Bets refunded")
+	}
+
+	g.CurrentVote.Bets = make(map[string]Bet)
+}
+
+// wallet returns the ledger key of a user, its Twitch user ID
+// balances stored by older versions are keyed by login, they are moved on first use
+func (g *Gambling) wallet(user twitch.User) string {
+	id := userID(user)
+
+	err := g.Ledger.Rename(user.Name, id)
+	if err != nil {
+		log.WithError(err).WithField("user", user.Name).Error("Error moving balance")
+	}
+
+	return id
+}
+
+// handle a bet
+func (g *Gambling) handleBet(user twitch.User, args []string) {
+
+	// Ensure the vote is open
+	if !g.CurrentVote.IsOpen {
+		log.Warn("Bet triggered while close")
+		return
+	}
+
+	// a bet is also a vote
+	if !g.eligible(user) {
+		return
+	}
+
+	if len(args) < 2 {
+		g.ack(user.Name, g.text("bet.usage", nil))
+		return
+	}
+
+	choice := g.CurrentVote.normalize(args[0])
+	if !g.isVoteValid(choice) {
+		g.ack(user.Name, g.text("bet.choice", vars{"Choice": choice}))
+		return
+	}
+
+	amount, err := strconv.ParseInt(args[1], 10, 64)
+	if err != nil || amount <= 0 {
+		g.ack(user.Name, g.text("bet.amount", vars{"Amount": args[1]}))
+		return
+	}
+
+	// a bet on a choice missing from a multi-select or ranked ballot adds it, as the least preferred one
+	choices := []string{choice}
+	if ballot := g.CurrentVote.ballot(userID(user)); (g.CurrentVote.mode() == modeMulti || g.CurrentVote.mode() == modeRanked) && len(ballot) > 0 {
+		choices = append([]string{}, ballot...)
+		if !inBallot(choices, choice) {
+			choices = append(choices, choice)
+		}
+
+		if g.CurrentVote.mode() == modeMulti && g.CurrentVote.MaxChoices > 0 && len(choices) > g.CurrentVote.MaxChoices {
+			g.ack(user.Name, g.text("bet.ballot", vars{"Choice": choice, "Max": g.CurrentVote.MaxChoices}))
+			return
+		}
+	}
+
+	// a new bet replaces the previous one, only the difference is debited
+	id := g.wallet(user)
+	previous := g.CurrentVote.Bets[id]
+	balance, err := g.Ledger.Add(id, previous.Amount-amount)
+	if errors.Is(err, ErrInsufficientFunds) {
+		g.ack(user.Name, g.text("bet.funds", vars{"Balance": balance + previous.Amount}))
+		return
+	}
+	if err != nil {
+		log.WithError(err).WithField("user", user.Name).Error("Error registering bet")
+		g.ack(user.Name, g.text("bet.error", nil))
+		return
+	}
+
+	// a bet is also a vote
+	g.CurrentVote.Bets[id] = Bet{Choice: choice, Amount: amount}
+	g.castVote(user, choices)
+
+	g.saveVote()
+	g.publish("vote")
+
+	log.WithFields(log.Fields{
+		"user":   user.Name,
+		"choice": choice,
+		"amount": amount,
+	}).Info("Bet registered")
+
+	g.ack(user.Name, g.text("bet", vars{"Amount": amount, "Choice": choice, "Balance": balance}))
+}
+
+// handle a call to a user balance
+func (g *Gambling) handleBalance(user twitch.User, args []string) {
+	g.sayAt(g.text("balance", vars{"Balance": g.Ledger.Balance(g.wallet(user))}), []string{user.Name})
+}
+
+// payBets is used to pay bets of the current vote once resolved
+// returns the number of winning bets and the pool they shared
+func (g *Gambling) payBets(outcome string) (int, int64) {
+	// a bet wins if its owner picked the outcome, or made one of the closest guesses of a numeric vote
+	won := func(user string, b Bet) bool {
+		if g.CurrentVote.mode() == modeNumber {
+			return g.CurrentVote.picked(user, outcome)
+		}
+		return b.Choice == outcome
+	}
+	gains := sharePool(g.CurrentVote.Bets, won)
+
+	var pool int64
+	for _, b := range g.CurrentVote.Bets {
+		pool += b.Amount
+	}
+
+	winners := 0
+	for u, gain := range gains {
+		balance, err := g.Ledger.Add(u, gain)
+		if err != nil {
+			log.WithError(err).WithField("user", u).Error("Error paying bet")
+			continue
+		}
+
+		if won(u, g.CurrentVote.Bets[u]) {
+			winners++
+			g.ack(g.CurrentVote.login(u), g.text("bet.won", vars{"Gain": gain, "Balance": balance}))
+		}
+	}
+
+	return winners, pool
+}
@@ -0,0 +1,182 @@
+package app
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPayouts(t *testing.T) {
+	bets := map[string]Bet{
+		"alice": {Choice: "val", Amount: 100},
+		"bob":   {Choice: "val", Amount: 300},
+		"carol": {Choice: "pl", Amount: 600},
+	}
+
+	assert.Equal(t, map[string]int64{"alice": 250, "bob": 750}, payouts(bets, "val"))
+	assert.Equal(t, map[string]int64{"carol": 1000}, payouts(bets, "pl"))
+
+	// nobody wins, everyone is refunded
+	assert.Equal(t, map[string]int64{"alice": 100, "bob": 300, "carol": 600}, payouts(bets, "draw"))
+
+	assert.Empty(t, payouts(nil, "val"))
+
+	// points left by rounding go to the largest winning bet, the first user for a tie
+	bets = map[string]Bet{
+		"alice": {Choice: "val", Amount: 10},
+		"bob":   {Choice: "val", Amount: 10},
+		"carol": {Choice: "val", Amount: 10},
+		"dave":  {Choice: "pl", Amount: 70},
+	}
+	gains := payouts(bets, "val")
+	assert.Equal(t, map[string]int64{"alice": 34, "bob": 33, "carol": 33}, gains)
+
+	bets["carol"] = Bet{Choice: "val", Amount: 11}
+	gains = payouts(bets, "val")
+	assert.Equal(t, map[string]int64{"alice": 32, "bob": 32, "carol": 37}, gains)
+
+	var total int64
+	for _, g := range gains {
+		total += g
+	}
+	assert.Equal(t, int64(101), total)
+}
+
+func TestBetLifecycle(t *testing.T) {
+	g, fake := newTestGambling(t, testConf())
+
+	fake.send("chan", "admin", "!gamble create val pl")
+	fake.Said()
+
+	fake.send("chan", "alice", "!gamble bet val 100")
+	fake.send("chan", "bob", "!gamble bet pl 2000")
+	fake.send("chan", "bob", "!gamble bet nope 20")
+	fake.send("chan", "bob", "!gamble bet pl -1")
+	fake.send("chan", "bob", "!gamble bet pl")
+	fake.send("chan", "bob", "!gamble bet pl 300")
+	// a new bet replaces the previous one
+	fake.send("chan", "alice", "!gamble bet val 200")
+	// a vote moves the bet
+	fake.send("chan", "carol", "!gamble bet pl 500")
+	fake.send("chan", "carol", "!gamble vote val")
+
+	assert.Equal(t, []sentMessage{
+		{To: "alice", Message: "Your bet of 100 points on val is registered, your balance is now 900 points (sent on 2020-04-02)"},
+		{To: "bob", Message: "Sorry but you only have 1000 points (sent on 2020-04-02)"},
+		{To: "bob", Message: "Sorry but nope is not a valid choice (choices are : val or pl) (sent on 2020-04-02)"},
+		{To: "bob", Message: "Sorry but -1 is not a valid amount of points (sent on 2020-04-02)"},
+		{To: "bob", Message: "Sorry but the bet command you send is not valid, use '!gamble bet <choice> <amount>' (sent on 2020-04-02)"},
+		{To: "bob", Message: "Your bet of 300 points on pl is registered, your balance is now 700 points (sent on 2020-04-02)"},
+		{To: "alice", Message: "Your bet of 200 points on val is registered, your balance is now 800 points (sent on 2020-04-02)"},
+		{To: "carol", Message: "Your bet of 500 points on pl is registered, your balance is now 500 points (sent on 2020-04-02)"},
+		{To: "carol", Message: "For your information, I correctly handled your vote for val (sent on 2020-04-02)"},
+	}, fake.Whispered())
+	assert.Equal(t, map[string]string{"id-alice": "val", "id-bob": "pl", "id-carol": "val"}, g.CurrentVote.Votes)
+
+	fake.send("chan", "admin", "!gamble resolve val")
+	assert.Equal(t, []string{"@admin  : Hey ! The vote isn't closed ! Close it using command : '!gamble close'"}, fake.Said())
+
+	fake.send("chan", "admin", "!gamble close")
+	fake.send("chan", "admin", "!gamble resolve nope")
+	fake.Said()
+
+	fake.send("chan", "admin", "!gamble resolve VAL")
+	assert.Equal(t, []string{"The result is val ! 2 viewers guessed right : alice, carol | 2 winning bets share a pool of 1000 points"}, fake.Said())
+	// the point left by rounding goes to the largest bet
+	assert.ElementsMatch(t, []sentMessage{
+		{To: "alice", Message: "Well done ! You won 285 points, your balance is now 1085 points (sent on 2020-04-02)"},
+		{To: "carol", Message: "Well done ! You won 715 points, your balance is now 1215 points (sent on 2020-04-02)"},
+	}, fake.Whispered())
+
+	fake.send("chan", "admin", "!gamble resolve pl")
+	assert.Equal(t, []string{"@admin  : This vote is already resolved, the result was val"}, fake.Said())
+
+	// resolved bets are not refunded
+	fake.send("chan", "admin", "!gamble delete")
+	fake.send("chan", "bob", "!gamble balance")
+	fake.Said()
+	fake.send("chan", "alice", "!gamble balance")
+	assert.Equal(t, []string{"@alice  : your balance is 1085 points"}, fake.Said())
+	assert.Equal(t, int64(700), g.Ledger.Balance("id-bob"))
+}
+
+func TestRefundBets(t *testing.T) {
+	g, fake := newTestGambling(t, testConf())
+
+	fake.send("chan", "admin", "!gamble create val pl")
+	fake.send("chan", "alice", "!gamble bet val 100")
+	fake.send("chan", "admin", "!gamble reset")
+	assert.Equal(t, int64(1000), g.Ledger.Balance("id-alice"))
+	assert.Empty(t, g.CurrentVote.Bets)
+
+	fake.send("chan", "alice", "!gamble bet val 100")
+	fake.send("chan", "admin", "!gamble delete")
+	assert.Equal(t, int64(1000), g.Ledger.Balance("id-alice"))
+
+	// nobody bet on the result
+	fake.send("chan", "admin", "!gamble create val pl")
+	fake.send("chan", "alice", "!gamble bet val 100")
+	fake.send("chan", "admin", "!gamble close")
+	fake.Said()
+	fake.send("chan", "admin", "!gamble resolve pl")
+	assert.Equal(t, []string{"The result is pl ! Nobody guessed right | Nobody bet on it, all bets are refunded"}, fake.Said())
+	assert.Equal(t, int64(1000), g.Ledger.Balance("id-alice"))
+}
+
+func TestBetLedgerError(t *testing.T) {
+	g, fake := newTestGambling(t, testConf())
+
+	fake.send("chan", "admin", "!gamble create val pl")
+	fake.Said()
+
+	// a ledger which can not be written does not mean funds are missing
+	g.Ledger.path = filepath.Join(t.TempDir(), "missing", ledgerFile)
+	fake.send("chan", "alice", "!gamble bet val 100")
+
+	assert.Equal(t, []sentMessage{
+		{To: "alice", Message: "Sorry but your bet could not be registered, please retry later (sent on 2020-04-02)"},
+	}, fake.Whispered())
+	assert.Equal(t, int64(1000), g.Ledger.Balance("id-alice"))
+	assert.Empty(t, g.CurrentVote.Bets)
+}
+
+func TestBetKeepsBallot(t *testing.T) {
+	g, fake := newTestGambling(t, testConf())
+
+	fake.send("chan", "admin", "!gamble create multi 2 a b c")
+	fake.send("chan", "alice", "!gamble vote a b")
+	fake.send("chan", "bob", "!gamble vote a")
+	fake.Said()
+	fake.Whispered()
+
+	// a bet on a picked choice keeps the ballot
+	fake.send("chan", "alice", "!gamble bet b 10")
+	assert.Equal(t, []string{"a", "b"}, g.CurrentVote.Ballots["id-alice"])
+
+	// a missing choice is added, up to the maximum number of choices
+	fake.send("chan", "bob", "!gamble bet c 10")
+	assert.Equal(t, []string{"a", "c"}, g.CurrentVote.Ballots["id-bob"])
+	fake.send("chan", "alice", "!gamble bet c 10")
+	assert.Equal(t, []string{"a", "b"}, g.CurrentVote.Ballots["id-alice"])
+	assert.Equal(t, Bet{Choice: "b", Amount: 10}, g.CurrentVote.Bets["id-alice"])
+
+	assert.Equal(t, []sentMessage{
+		{To: "alice", Message: "Your bet of 10 points on b is registered, your balance is now 990 points (sent on 2020-04-02)"},
+		{To: "bob", Message: "Your bet of 10 points on c is registered, your balance is now 990 points (sent on 2020-04-02)"},
+		{To: "alice", Message: "Sorry but you already picked 2 choices, vote again with c to bet on it (sent on 2020-04-02)"},
+	}, fake.Whispered())
+
+	// voting again keeps a bet on a choice still picked
+	fake.send("chan", "alice", "!gamble vote b a")
+	assert.Equal(t, "b", g.CurrentVote.Bets["id-alice"].Choice)
+	fake.send("chan", "alice", "!gamble vote a")
+	assert.Equal(t, "a", g.CurrentVote.Bets["id-alice"].Choice)
+
+	// ranked ballots keep their order, a missing choice is the least preferred one
+	fake.send("chan", "admin", "!gamble delete")
+	fake.send("chan", "admin", "!gamble create ranked a b c")
+	fake.send("chan", "alice", "!gamble vote b a")
+	fake.send("chan", "alice", "!gamble bet c 10")
+	assert.Equal(t, []string{"b", "a", "c"}, g.CurrentVote.Ballots["id-alice"])
+}
@@ -0,0 +1,115 @@
+package app
+
+import (
+	"math/rand"
+
+	twitch "github.com/gempir/go-twitch-irc/v2"
+)
+
+// WeightedTally is a structure containing the weight of votes for a choice
+type WeightedTally struct {
+	Choice  string  `json:"choice"`
+	Weight  float64 `json:"weight"`
+	Percent float64 `json:"percent"`
+}
+
+// of returns the weight of a user vote :
+// the highest weight among its roles and subscription tier, or the weight of everyone (1 by default)
+// weights which are not positive are ignored
+func (w Weights) of(user twitch.User) float64 {
+	weight := 1.0
+	if base, ok := w.Roles[everyone]; ok && base > 0 {
+		weight = base
+	}
+
+	matched := 0.0
+	for role, rw := range w.Roles {
+		if role == everyone || rw <= 0 {
+			continue
+		}
+		if _, ok := user.Badges[role]; ok && rw > matched {
+			matched = rw
+		}
+	}
+	if tw, ok := w.Tiers[subTier(user)]; ok && tw > matched {
+		matched = tw
+	}
+
+	if matched > 0 {
+		return matched
+	}
+
+	return weight
+}
+
+// weight returns the weight of a voter, votes stored by older versions weigh 1
+func (v *Vote) weight(id string) float64 {
+	if w := v.Voters[id].Weight; w > 0 {
+		return w
+	}
+
+	return 1
+}
+
+// weighted returns true if a voter does not weigh 1
+func (v *Vote) weighted() bool {
+	for u := range v.Votes {
+		if v.weight(u) != 1 {
+			return true
+		}
+	}
+
+	return false
+}
+
+// weightedTallies returns the weight of each choice, following choices order
+// percents are based on the total weight of participants
+func weightedTallies(v *Vote, st Statistics) []WeightedTally {
+	res := []WeightedTally{}
+
+	for _, c := range v.choiceList(st) {
+		t := WeightedTally{Choice: c, Weight: st.Weighted[c]}
+		if st.TotalWeight > 0 {
+			t.Percent = t.Weight / st.TotalWeight * 100
+		}
+		res = append(res, t)
+	}
+
+	return res
+}
+
+// weightedSummary returns the weighted results announced when a vote is closed, empty if nobody has a weight
+func weightedSummary(c *Catalogue, v *Vote, st Statistics) string {
+	if st.Weighted == nil {
+		return ""
+	}
+
+	var parts []string
+	for _, t := range weightedTallies(v, st) {
+		if t.Weight == 0 {
+			continue
+		}
+		parts = append(parts, c.render("stats.weight", vars{"Choice": t.Choice, "Weight": formatNumber(t.Weight), "Percent": t.Percent}))
+	}
+
+	return c.render("stats.weighted", vars{"Tallies": parts})
+}
+
+// drawTicket is used to pick a candidate using a generator, each one having as many tickets as its weight
+func drawTicket(r *rand.Rand, v *Vote, candidates []string) string {
+	var total float64
+	for _, c := range candidates {
+		total += v.weight(c)
+	}
+
+	n := r.Float64() * total
+	for _, c := range candidates {
+		n -= v.weight(c)
+		if n < 0 {
+			return c
+		}
+	}
+
+	// rounding errors, the last candidate wins
+	return candidates[len(candidates)-1]
+}
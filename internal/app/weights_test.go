@@ -0,0 +1,85 @@
+package app
+
+import (
+	"math/rand"
+	"testing"
+
+	twitch "github.com/gempir/go-twitch-irc/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWeightOf(t *testing.T) {
+	w := Weights{
+		Roles: map[string]float64{"vip": 1.5, "moderator": 0},
+		Tiers: map[int]float64{1: 2, 3: 4},
+	}
+
+	assert.Equal(t, 1.0, w.of(twitch.User{}))
+	assert.Equal(t, 1.5, w.of(twitch.User{Badges: map[string]int{"vip": 1}}))
+	// weights which are not positive are ignored
+	assert.Equal(t, 1.0, w.of(twitch.User{Badges: map[string]int{"moderator": 1}}))
+	// the highest weight applies
+	assert.Equal(t, 2.0, w.of(twitch.User{Badges: map[string]int{"vip": 1, "subscriber": 6}}))
+	assert.Equal(t, 4.0, w.of(twitch.User{Badges: map[string]int{"subscriber": 3012}}))
+	assert.Equal(t, 1.0, w.of(twitch.User{Badges: map[string]int{"subscriber": 2012}}))
+
+	w.Roles[everyone] = 0.5
+	assert.Equal(t, 0.5, w.of(twitch.User{}))
+	assert.Equal(t, 1.5, w.of(twitch.User{Badges: map[string]int{"vip": 1}}))
+
+	// votes stored by older versions weigh 1
+	assert.Equal(t, 1.0, (&Vote{}).weight("alice"))
+}
+
+func TestDrawTicket(t *testing.T) {
+	v := &Vote{Voters: map[string]Voter{"a": {Weight: 1}, "b": {Weight: 3}}}
+
+	r := rand.New(cryptoSource{})
+	counts := make(map[string]int)
+	for i := 0; i < 4000; i++ {
+		counts[drawTicket(r, v, []string{"a", "b"})]++
+	}
+
+	// b has 3 times more tickets than a
+	assert.InDelta(t, 3000, counts["b"], 200)
+}
+
+func TestWeightedVote(t *testing.T) {
+	conf := testConf()
+	conf.Weights = Weights{Tiers: map[int]float64{1: 2}, Tickets: true}
+	g, fake := newTestGambling(t, conf)
+
+	fake.send("chan", "admin", "!gamble create val pl")
+	fake.sendAs("chan", twitch.User{ID: "1", Name: "alice", Badges: map[string]int{"subscriber": 3}}, "!gamble vote val")
+	fake.send("chan", "bob", "!gamble vote pl")
+	fake.send("chan", "carol", "!gamble vote pl")
+	fake.Said()
+
+	fake.send("chan", "admin", "!gamble close")
+	assert.Equal(t, []string{"Vote is now closed, time for statistics ! Participants : 3 | val : 1 (33.33%), pl : 2 (66.67%) | Weighted : val : 2 (50.00%), pl : 2 (50.00%)"}, fake.Said())
+
+	stats := createStat(g.CurrentVote)
+	assert.Contains(t, stats, "val (1, weight 2): alice\n")
+	assert.Contains(t, stats, "pl (2, weight 2): bob, carol\n")
+
+	report := NewReport(g.CurrentVote)
+	assert.Equal(t, 4.0, report.TotalWeight)
+	assert.Equal(t, 2.0, report.Choices[0].Weight)
+	assert.Equal(t, 50.0, report.Choices[0].WeightedPercent)
+
+	data, err := createCSVStat(g.CurrentVote)
+	assert.Nil(t, err)
+	assert.Contains(t, string(data), ",val,alice,1,2,false,false\n")
+
+	assert.Equal(t, []WeightedTally{{"val", 2, 50}, {"pl", 2, 50}}, g.state().Weighted)
+
+	// nobody has a weight, results are not weighted
+	fake.send("chan", "admin", "!gamble create val pl")
+	fake.send("chan", "bob", "!gamble vote pl")
+	fake.send("chan", "admin", "!gamble close")
+	assert.Equal(t, []string{
+		"There is a new vote! You can vote with '!gamble vote <vote> (choices are : val or pl)'",
+		"Vote is now closed, time for statistics ! Participants : 1 | pl : 1 (100.00%)",
+	}, fake.Said())
+	assert.Nil(t, g.state().Weighted)
+}